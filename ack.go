@@ -0,0 +1,113 @@
+package snstesting
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// maxBatchSize is the limit of entries in single SQS batch request.
+const maxBatchSize = 10
+
+// Ack deletes messages from ad-hoc queue, so they are never received again.
+// Messages are deleted on receipt by default, so Ack is needed only with WithManualAck. Acked messages are skipped.
+func (s *Subscriber) Ack(ctx context.Context, msgs ...*Message) error {
+	var toDelete []*Message
+	var receiptHandles []string
+	for _, m := range msgs {
+		if m != nil && !m.deleted {
+			toDelete = append(toDelete, m)
+			receiptHandles = append(receiptHandles, m.ReceiptHandle)
+		}
+	}
+
+	deleted, err := s.deleteMessages(ctx, receiptHandles)
+	for i, m := range toDelete {
+		m.deleted = deleted[i]
+	}
+	if err != nil {
+		return fmt.Errorf("delete message failure: %v", err)
+	}
+	return nil
+}
+
+// Nack makes messages visible in ad-hoc queue again, so they are received once more.
+// Works with WithManualAck only, as otherwise messages are already gone from the queue, see Release for alternative.
+func (s *Subscriber) Nack(ctx context.Context, msgs ...*Message) error {
+	for _, m := range msgs {
+		if m == nil {
+			continue
+		}
+		if m.deleted {
+			return fmt.Errorf("message %s already deleted from the queue, use Release instead", m.ReceiptHandle)
+		}
+		_, err := s.SQS.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String(s.Config.QueueURL),
+			ReceiptHandle:     aws.String(m.ReceiptHandle),
+			VisibilityTimeout: 0,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release puts already received messages back, so they are returned first by subsequent receive calls.
+// Unlike Nack, it works locally, without touching the queue, so it's fine to use with auto acknowledgement.
+func (s *Subscriber) Release(msgs ...*Message) {
+	s.buffer().pushFront(msgs...)
+}
+
+// deleteMessages deletes messages by receipt handles, in batches if there is more than one.
+// Tells which of the messages were deleted, as batches may fail partially.
+func (s *Subscriber) deleteMessages(ctx context.Context, receiptHandles []string) ([]bool, error) {
+	deleted := make([]bool, len(receiptHandles))
+
+	if len(receiptHandles) == 1 {
+		_, err := s.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(s.Config.QueueURL),
+			ReceiptHandle: aws.String(receiptHandles[0]),
+		})
+		deleted[0] = err == nil
+		return deleted, err
+	}
+
+	var errs []error
+	for offset := 0; offset < len(receiptHandles); offset += maxBatchSize {
+		end := offset + maxBatchSize
+		if end > len(receiptHandles) {
+			end = len(receiptHandles)
+		}
+
+		entries := make([]types.DeleteMessageBatchRequestEntry, 0, end-offset)
+		for i := offset; i < end; i++ {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i - offset)),
+				ReceiptHandle: aws.String(receiptHandles[i]),
+			})
+		}
+
+		out, err := s.SQS.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(s.Config.QueueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return deleted, err
+		}
+
+		failed := map[string]bool{}
+		for _, f := range out.Failed {
+			failed[aws.ToString(f.Id)] = true
+			errs = append(errs, fmt.Errorf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message)))
+		}
+		for i := offset; i < end; i++ {
+			deleted[i] = !failed[strconv.Itoa(i-offset)]
+		}
+	}
+	return deleted, combineErr(errs...)
+}
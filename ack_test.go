@@ -0,0 +1,234 @@
+package snstesting_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveManualAck(t *testing.T, ctx context.Context, SQS *mock.MockSQSAPI, subscriber *snstesting.Subscriber, receipts ...string) []*snstesting.Message {
+	t.Helper()
+
+	var msgs []*snstesting.Message
+	for _, receipt := range receipts {
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "hello"}`), ReceiptHandle: aws.String(receipt)},
+				},
+			}, nil)
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		if assert.NoError(t, err) && assert.NotNil(t, msg) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func TestSubscriber_Ack(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("single message", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url", ManualAck: true},
+		}
+		msgs := receiveManualAck(t, ctx, SQS, subscriber, "receipt")
+
+		SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String("http://queue.url"),
+			ReceiptHandle: aws.String("receipt"),
+		}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+		assert.NoError(t, subscriber.Ack(ctx, msgs...))
+		// already acked
+		assert.NoError(t, subscriber.Ack(ctx, msgs...))
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url", ManualAck: true},
+		}
+		msgs := receiveManualAck(t, ctx, SQS, subscriber, "r0", "r1", "r2", "r3", "r4", "r5", "r6", "r7", "r8", "r9", "r10", "r11")
+
+		SQS.EXPECT().DeleteMessageBatch(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+			Do(func(ctx context.Context, input *sqs.DeleteMessageBatchInput, opts ...func(*sqs.Options)) {
+				assert.Equal(t, "http://queue.url", aws.ToString(input.QueueUrl))
+				assert.Len(t, input.Entries, 10)
+				assert.Equal(t, "r0", aws.ToString(input.Entries[0].ReceiptHandle))
+			}).
+			Return(&sqs.DeleteMessageBatchOutput{}, nil)
+		SQS.EXPECT().DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String("http://queue.url"),
+			Entries: []sqstypes.DeleteMessageBatchRequestEntry{
+				{Id: aws.String("0"), ReceiptHandle: aws.String("r10")},
+				{Id: aws.String("1"), ReceiptHandle: aws.String("r11")},
+			},
+		}).Return(&sqs.DeleteMessageBatchOutput{}, nil)
+
+		assert.NoError(t, subscriber.Ack(ctx, msgs...))
+	})
+
+	t.Run("batch failures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url", ManualAck: true},
+		}
+		msgs := receiveManualAck(t, ctx, SQS, subscriber, "r0", "r1")
+
+		SQS.EXPECT().DeleteMessageBatch(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+			Return(&sqs.DeleteMessageBatchOutput{
+				Failed: []sqstypes.BatchResultErrorEntry{
+					{Id: aws.String("1"), Code: aws.String("ReceiptHandleIsInvalid"), Message: aws.String("invalid")},
+				},
+			}, nil)
+
+		err := subscriber.Ack(ctx, msgs...)
+		assert.EqualError(t, err, "delete message failure: ReceiptHandleIsInvalid: invalid")
+
+		// only the failed one is retried
+		SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String("http://queue.url"),
+			ReceiptHandle: aws.String("r1"),
+		}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+		assert.NoError(t, subscriber.Ack(ctx, msgs...))
+	})
+
+	t.Run("auto ack", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "hello"}`), ReceiptHandle: aws.String("receipt")},
+				},
+			}, nil)
+		SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String("http://queue.url"),
+			ReceiptHandle: aws.String("receipt"),
+		}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, subscriber.Ack(ctx, msg))
+	})
+
+	t.Run("auto ack failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "hello"}`), ReceiptHandle: aws.String("receipt")},
+				},
+			}, nil)
+		SQS.EXPECT().DeleteMessage(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageInput{})).
+			Return(nil, errors.New("foo"))
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.EqualError(t, err, "delete message failure: foo")
+		assert.Nil(t, msg)
+	})
+}
+
+func TestSubscriber_Nack(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("manual ack", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url", ManualAck: true},
+		}
+		msgs := receiveManualAck(t, ctx, SQS, subscriber, "receipt")
+
+		SQS.EXPECT().ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String("http://queue.url"),
+			ReceiptHandle:     aws.String("receipt"),
+			VisibilityTimeout: 0,
+		}).Return(&sqs.ChangeMessageVisibilityOutput{}, nil)
+
+		assert.NoError(t, subscriber.Nack(ctx, msgs...))
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "hello"}`), ReceiptHandle: aws.String("receipt")},
+				},
+			}, nil)
+		SQS.EXPECT().DeleteMessage(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageInput{})).
+			Return(&sqs.DeleteMessageOutput{}, nil)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.Error(t, subscriber.Nack(ctx, msg))
+	})
+}
+
+func TestSubscriber_Release(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+
+	subscriber := &snstesting.Subscriber{
+		SQS:    SQS,
+		Config: snstesting.Config{QueueURL: "http://queue.url", ManualAck: true},
+	}
+	msgs := receiveManualAck(t, ctx, SQS, subscriber, "r0", "r1")
+
+	subscriber.Release(msgs...)
+
+	// no more calls to SQS, released messages come first
+	msg, err := subscriber.ReceiveMessage(ctx)
+	assert.NoError(t, err)
+	assert.Same(t, msgs[0], msg)
+
+	body, err := subscriber.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Message": "hello"}`, body)
+}
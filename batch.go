@@ -0,0 +1,112 @@
+package snstesting
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// ReceiveBatch receives up to max messages, asking SQS for up to 10 of them per call.
+// Stops early, returning fewer messages, once long polling brings nothing.
+// Messages kept aside by previous calls are returned first.
+func (s *Subscriber) ReceiveBatch(ctx context.Context, max int) ([]*Message, error) {
+	var msgs []*Message
+	for len(msgs) < max {
+		m := s.buffer().pop()
+		if m == nil {
+			break
+		}
+		msgs = append(msgs, m)
+	}
+
+	for len(msgs) < max {
+		n := max - len(msgs)
+		if n > maxBatchSize {
+			n = maxBatchSize
+		}
+
+		batch, err := s.receiveMessages(ctx, int32(n), s.waitTimeSeconds())
+		if err != nil {
+			// do not lose what was collected so far
+			s.buffer().pushFront(msgs...)
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		msgs = append(msgs, batch...)
+	}
+	return msgs, nil
+}
+
+// drainEmptyReceives limits receives bringing nothing after the quiet period, while approximate number of messages
+// says there are more. Visible messages may not be receivable, e.g. FIFO group blocked by message not acknowledged yet.
+const drainEmptyReceives = 3
+
+// Drain receives everything until no new messages arrive for the quiet period.
+// Approximate number of messages in the queue is consulted before giving up, so messages still visible are not missed.
+// Once quiet period is over, a few receives bringing nothing end it anyway, as approximate number may lag behind
+// or count messages which can't be received. Messages kept aside by previous calls are returned first.
+func (s *Subscriber) Drain(ctx context.Context, quietPeriod time.Duration) ([]*Message, error) {
+	var msgs []*Message
+	for {
+		m := s.buffer().pop()
+		if m == nil {
+			break
+		}
+		msgs = append(msgs, m)
+	}
+
+	lastArrival := time.Now()
+	empty := 0
+	for {
+		quiet := time.Since(lastArrival)
+		if quiet >= quietPeriod {
+			if empty >= drainEmptyReceives {
+				return msgs, nil
+			}
+			n, err := s.approximateNumberOfMessages(ctx)
+			if err != nil {
+				s.buffer().pushFront(msgs...)
+				return nil, err
+			}
+			if n == 0 {
+				return msgs, nil
+			}
+		}
+
+		batch, err := s.receiveMessages(ctx, maxBatchSize, s.waitTimeSecondsWithin(quietPeriod-quiet))
+		if err != nil {
+			s.buffer().pushFront(msgs...)
+			return nil, err
+		}
+		if len(batch) > 0 {
+			msgs = append(msgs, batch...)
+			lastArrival = time.Now()
+			empty = 0
+		} else if quiet >= quietPeriod {
+			empty++
+		}
+	}
+}
+
+func (s *Subscriber) approximateNumberOfMessages(ctx context.Context) (int, error) {
+	out, err := s.SQS.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(s.Config.QueueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameApproximateNumberOfMessages},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("get queue attributes failure: %v", err)
+	}
+
+	v, ok := out.Attributes[string(types.QueueAttributeNameApproximateNumberOfMessages)]
+	if !ok {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,173 @@
+package snstesting_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func sqsMessages(from, to int) []sqstypes.Message {
+	var msgs []sqstypes.Message
+	for i := from; i < to; i++ {
+		msgs = append(msgs, sqstypes.Message{
+			Body:          aws.String(fmt.Sprintf(`{"Message": "%d"}`, i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("receipt %d", i)),
+		})
+	}
+	return msgs
+}
+
+func TestSubscriber_ReceiveBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		receive := func(max int32, msgs []sqstypes.Message) *gomock.Call {
+			return SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String("http://queue.url"),
+				MaxNumberOfMessages: max,
+				VisibilityTimeout:   3600,
+				WaitTimeSeconds:     3,
+			}).Return(&sqs.ReceiveMessageOutput{Messages: msgs}, nil)
+		}
+		gomock.InOrder(
+			receive(10, sqsMessages(1, 11)),
+			receive(10, sqsMessages(11, 21)),
+			receive(4, sqsMessages(21, 23)),
+			receive(2, nil),
+		)
+		SQS.EXPECT().DeleteMessageBatch(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+			Return(&sqs.DeleteMessageBatchOutput{}, nil).Times(3)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+		subscriber.Release(&snstesting.Message{Message: "0"})
+
+		msgs, err := subscriber.ReceiveBatch(ctx, 25)
+		assert.NoError(t, err)
+		if assert.Len(t, msgs, 23) {
+			for i, msg := range msgs {
+				assert.Equal(t, fmt.Sprint(i), msg.Message)
+			}
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		gomock.InOrder(
+			SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(&sqs.ReceiveMessageOutput{Messages: sqsMessages(0, 10)}, nil),
+			SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(nil, assert.AnError),
+		)
+		SQS.EXPECT().DeleteMessageBatch(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+			Return(&sqs.DeleteMessageBatchOutput{}, nil)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msgs, err := subscriber.ReceiveBatch(ctx, 20)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Empty(t, msgs)
+
+		// messages received before the error are not lost
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "0", msg.Message)
+		}
+	})
+}
+
+func TestSubscriber_Drain(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+
+	pending := sqsMessages(0, 10)
+	SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+		DoAndReturn(func(ctx context.Context, input *sqs.ReceiveMessageInput, opts ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+			assert.Equal(t, int32(10), input.MaxNumberOfMessages)
+			if len(pending) > 0 {
+				msgs := pending
+				pending = nil
+				return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+			}
+			time.Sleep(10 * time.Millisecond)
+			return &sqs.ReceiveMessageOutput{}, nil
+		}).MinTimes(3)
+	SQS.EXPECT().DeleteMessageBatch(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+		Return(&sqs.DeleteMessageBatchOutput{}, nil).Times(2)
+
+	var approximated int
+	SQS.EXPECT().GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String("http://queue.url"),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameApproximateNumberOfMessages},
+	}).DoAndReturn(func(ctx context.Context, input *sqs.GetQueueAttributesInput, opts ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+		approximated++
+		if approximated == 1 {
+			// late messages, visible in the queue, but not yet received
+			pending = sqsMessages(10, 13)
+			return &sqs.GetQueueAttributesOutput{Attributes: map[string]string{"ApproximateNumberOfMessages": "3"}}, nil
+		}
+		return &sqs.GetQueueAttributesOutput{Attributes: map[string]string{"ApproximateNumberOfMessages": "0"}}, nil
+	}).Times(2)
+
+	subscriber := &snstesting.Subscriber{
+		SQS:    SQS,
+		Config: snstesting.Config{QueueURL: "http://queue.url"},
+	}
+
+	msgs, err := subscriber.Drain(ctx, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 13)
+}
+
+func TestSubscriber_Drain_Stuck(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+
+	pending := sqsMessages(0, 2)
+	SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+		DoAndReturn(func(ctx context.Context, input *sqs.ReceiveMessageInput, opts ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+			msgs := pending
+			pending = nil
+			time.Sleep(10 * time.Millisecond)
+			return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+		}).MinTimes(4)
+	SQS.EXPECT().DeleteMessageBatch(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+		Return(&sqs.DeleteMessageBatchOutput{}, nil)
+	// e.g. FIFO group blocked by message not acknowledged yet, it's visible, but can't be received
+	SQS.EXPECT().GetQueueAttributes(ctx, gomock.Any()).
+		Return(&sqs.GetQueueAttributesOutput{Attributes: map[string]string{"ApproximateNumberOfMessages": "1"}}, nil).
+		Times(3)
+
+	subscriber := &snstesting.Subscriber{
+		SQS:    SQS,
+		Config: snstesting.Config{QueueURL: "http://queue.url"},
+	}
+
+	msgs, err := subscriber.Drain(ctx, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2)
+}
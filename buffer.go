@@ -0,0 +1,66 @@
+package snstesting
+
+import (
+	"sync"
+)
+
+func (s *Subscriber) buffer() *messageBuffer {
+	if s.pending == nil {
+		s.pending = &messageBuffer{}
+	}
+	return s.pending
+}
+
+// messageBuffer keeps messages that were received from the queue, but not yet handed over to the caller.
+type messageBuffer struct {
+	mu   sync.Mutex
+	msgs []*Message
+}
+
+func (b *messageBuffer) pushFront(msgs ...*Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var v []*Message
+	for _, m := range msgs {
+		if m != nil {
+			v = append(v, m)
+		}
+	}
+	b.msgs = append(v, b.msgs...)
+}
+
+func (b *messageBuffer) pushBack(msgs ...*Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.msgs = append(b.msgs, msgs...)
+}
+
+// take removes and returns first message matching the predicate.
+func (b *messageBuffer) take(match Predicate) *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, m := range b.msgs {
+		if match(m) {
+			b.msgs = append(b.msgs[:i:i], b.msgs[i+1:]...)
+			return m
+		}
+	}
+	return nil
+}
+
+func (b *messageBuffer) snapshot() []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Message(nil), b.msgs...)
+}
+
+func (b *messageBuffer) pop() *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.msgs) == 0 {
+		return nil
+	}
+	m := b.msgs[0]
+	b.msgs = b.msgs[1:]
+	return m
+}
@@ -0,0 +1,330 @@
+package snstesting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// defaultCleanupTimeout bounds cleanup run by test helpers, and cleanup with ctx without deadline.
+	defaultCleanupTimeout = time.Minute
+	// cleanupBackoff is delay after the first failed attempt, doubled after every next one up to cleanupMaxBackoff.
+	cleanupBackoff    = 100 * time.Millisecond
+	cleanupMaxBackoff = 5 * time.Second
+)
+
+// Leak is ad-hoc resource Cleanup failed to remove, either subscription or queue.
+// Leaks are recorded in the leak ledger as JSON lines, see WithLeakLedger and Sweeper.SweepLedger.
+type Leak struct {
+	SubscriptionARN string `json:"subscription_arn,omitempty"`
+	QueueURL        string `json:"queue_url,omitempty"`
+	// Reason tells why the resource was not removed.
+	Reason   string    `json:"reason"`
+	TestName string    `json:"test,omitempty"`
+	Owner    string    `json:"owner,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+func (l Leak) String() string {
+	return l.resource() + ": " + l.Reason
+}
+
+// resource gives kind and ARN or URL of leaked resource.
+func (l Leak) resource() string {
+	if l.SubscriptionARN != "" {
+		return "subscription " + l.SubscriptionARN
+	}
+	return "queue " + l.QueueURL
+}
+
+// LeakError is returned by Cleanup when some ad-hoc resources were left behind.
+type LeakError struct {
+	Leaks []Leak
+	// Ledger is failure of recording leaks in the leak ledger, if any.
+	Ledger error
+}
+
+func (e *LeakError) Error() string {
+	var v []string
+	for _, l := range e.Leaks {
+		v = append(v, "leaked "+l.String())
+	}
+	if e.Ledger != nil {
+		v = append(v, fmt.Sprintf("append leak ledger failure: %v", e.Ledger))
+	}
+	return strings.Join(v, ",")
+}
+
+// cleanupTimeout bounds cleanup run by test helpers.
+func (s *Subscriber) cleanupTimeout() time.Duration {
+	if s.Config.CleanupTimeout == 0 {
+		return defaultCleanupTimeout
+	}
+	return s.Config.CleanupTimeout
+}
+
+// leaked gives leaks described with details of the subscriber, for the ledger and the reports.
+func (s *Subscriber) leaked(leaks []Leak) error {
+	if len(leaks) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	for i := range leaks {
+		leaks[i].TestName = s.Config.TestName
+		leaks[i].Owner = s.Config.Owner
+		leaks[i].Time = now
+	}
+
+	err := &LeakError{Leaks: leaks}
+	if s.Config.LeakLedger != "" {
+		err.Ledger = appendLedger(s.Config.LeakLedger, leaks)
+	}
+	return err
+}
+
+// reportLeaks reports failed cleanup with t.Errorf, as t.Fatal must not be called from cleanup functions.
+func reportLeaks(t TB, err error) {
+	var leakErr *LeakError
+	if !errors.As(err, &leakErr) {
+		if err != nil {
+			t.Errorf("cleanup failure: %v", err)
+		}
+		return
+	}
+	for _, l := range leakErr.Leaks {
+		t.Errorf("snstesting: leaked %s", l)
+	}
+	if leakErr.Ledger != nil {
+		t.Errorf("snstesting: append leak ledger failure: %v", leakErr.Ledger)
+	}
+}
+
+// removeSubscription unsubscribes and checks the subscription is gone, one already gone is not an error.
+// When the check is not permitted, subscription is assumed removed and *unverifiedError is returned.
+func removeSubscription(ctx context.Context, SNS SNSAPI, subscriptionARN string) error {
+	return retryCleanup(ctx, func() error {
+		if err := unsubscribe(ctx, SNS, subscriptionARN); err != nil && !isNotFound(err) {
+			return err
+		}
+		_, err := SNS.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{
+			SubscriptionArn: aws.String(subscriptionARN),
+		})
+		if isNotFound(err) {
+			return nil
+		}
+		if isAuthError(err) {
+			return &unverifiedError{resource: "subscription " + subscriptionARN, err: err}
+		}
+		if err != nil {
+			return err
+		}
+		return errStillExists
+	})
+}
+
+// removeQueue deletes the queue and checks it's gone, one already gone is not an error. Only deletion is retried,
+// SQS keeps deleted queue visible for up to a minute, so when the check is not permitted, or the queue is still there,
+// queue is assumed removed and *unverifiedError is returned.
+func removeQueue(ctx context.Context, SQS SQSAPI, queueURL string) error {
+	err := retryCleanup(ctx, func() error {
+		if err := cleanupQueue(ctx, SQS, queueURL); err != nil && !isQueueGone(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = SQS.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if isQueueGone(err) {
+		return nil
+	}
+	if err == nil {
+		err = errDeletionPending
+	}
+	return &unverifiedError{resource: "queue " + queueURL, err: err}
+}
+
+// unverifiedError tells that resource was removed, but the removal could not be confirmed, for lack of permissions
+// or as deleted queue is still visible.
+// It's not a leak, CI roles that may remove resources don't have to be allowed to read them.
+type unverifiedError struct {
+	resource string
+	err      error
+}
+
+func (e *unverifiedError) Error() string {
+	return fmt.Sprintf("%s removed, unverified: %v", e.resource, e.err)
+}
+
+// unverified tells if err is *unverifiedError, so the resource counts as removed.
+func unverified(err error) bool {
+	var u *unverifiedError
+	return errors.As(err, &u)
+}
+
+// errStillExists tells that resource was reported removed, but it's still there.
+var errStillExists = errors.New("still exists after removal")
+
+// errDeletionPending tells that queue was deleted, but SQS still shows it, which lasts up to a minute.
+var errDeletionPending = errors.New("deletion in progress")
+
+// retryCleanup calls f until it succeeds, fails for good or ctx is done, defaultCleanupTimeout bounds ctx
+// without deadline. Throttling and transient failures are retried with exponential backoff.
+func retryCleanup(ctx context.Context, f func() error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		// calls made by f keep ctx of the caller, only retries are bounded
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCleanupTimeout)
+		defer cancel()
+	}
+
+	backoff := cleanupBackoff
+	for {
+		err := f()
+		if err == nil || !retryableCleanupError(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%v (gave up: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > cleanupMaxBackoff {
+			backoff = cleanupMaxBackoff
+		}
+	}
+}
+
+// transientErrorCodes are codes of SNS and SQS errors worth retrying, next to the ones retried by SDK itself.
+var transientErrorCodes = map[string]bool{
+	"Throttled":          true,
+	"KMSThrottling":      true,
+	"InternalError":      true,
+	"InternalFailure":    true,
+	"ServiceUnavailable": true,
+}
+
+func retryableCleanupError(err error) bool {
+	if errors.Is(err, errStillExists) {
+		return true
+	}
+	if retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorFault() == smithy.FaultServer || transientErrorCodes[apiErr.ErrorCode()]
+	}
+	return false
+}
+
+// authErrorCodes are codes of SNS, SQS and KMS errors telling that caller is not permitted to make the call.
+var authErrorCodes = map[string]bool{
+	"AuthorizationError":    true,
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+}
+
+// isAuthError tells that caller is not permitted to make the call.
+func isAuthError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && authErrorCodes[apiErr.ErrorCode()]
+}
+
+// isNotFound tells that SNS resource does not exist.
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == (&snstypes.NotFoundException{}).ErrorCode()
+}
+
+// isQueueGone tells that queue does not exist.
+func isQueueGone(err error) bool {
+	// SQS query protocol gives typed error for some operations only, others report just the code
+	var notExist *types.QueueDoesNotExist
+	var apiErr smithy.APIError
+	return errors.As(err, &notExist) || (errors.As(err, &apiErr) && apiErr.ErrorCode() == (&types.QueueDoesNotExist{}).ErrorCode())
+}
+
+// appendLedger appends leaks to the ledger file, one JSON line each. The file is created when missing.
+func appendLedger(path string, leaks []Leak) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, l := range leaks {
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	// single write, so lines of tests running in parallel don't interleave
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeLedger replaces content of the ledger file with given leaks, the file is removed when there are none.
+func writeLedger(path string, leaks []Leak) error {
+	if len(leaks) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.Truncate(path, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return appendLedger(path, leaks)
+}
+
+// ReadLedger reads leaks recorded in the leak ledger, see WithLeakLedger. Missing ledger has no leaks.
+func ReadLedger(path string) ([]Leak, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read leak ledger failure: %v", err)
+	}
+
+	var leaks []Leak
+	for i, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var l Leak
+		if err := json.Unmarshal([]byte(line), &l); err != nil {
+			return nil, fmt.Errorf("parse line %d of leak ledger failure: %v", i+1, err)
+		}
+		leaks = append(leaks, l)
+	}
+	return leaks, nil
+}
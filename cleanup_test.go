@@ -0,0 +1,272 @@
+package snstesting_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectGone expects Cleanup to check removal of the queue and subscriptions, and find them gone.
+func expectGone(ctx interface{}, SNS *mock.MockSNSAPI, SQS *mock.MockSQSAPI, queueURL string, subscriptionARNs ...string) {
+	for _, arn := range subscriptionARNs {
+		SNS.EXPECT().GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{SubscriptionArn: aws.String(arn)}).
+			Return(nil, &snstypes.NotFoundException{Message: aws.String("Subscription does not exist")})
+	}
+	SQS.EXPECT().GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	}).Return(nil, &smithy.GenericAPIError{Code: "AWS.SimpleQueueService.NonExistentQueue"})
+}
+
+func TestSubscriber_Cleanup_Retries(t *testing.T) {
+	ctx := context.Background()
+	throttled := &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}
+
+	newSubscriber := func(SNS snstesting.SNSAPI, SQS snstesting.SQSAPI) snstesting.Subscriber {
+		return snstesting.Subscriber{
+			SNS: SNS,
+			SQS: SQS,
+			Config: snstesting.Config{
+				QueueURL:        "http://queue.url",
+				SubscriptionARN: "arn:foo:bar:subscription",
+				TestName:        "TestOrders",
+				Owner:           "jenkins@ci",
+			},
+		}
+	}
+
+	t.Run("throttling", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		unsubscribe := &sns.UnsubscribeInput{SubscriptionArn: aws.String("arn:foo:bar:subscription")}
+		deleteQueue := &sqs.DeleteQueueInput{QueueUrl: aws.String("http://queue.url")}
+		gomock.InOrder(
+			SNS.EXPECT().Unsubscribe(ctx, unsubscribe).Return(nil, throttled),
+			SNS.EXPECT().Unsubscribe(ctx, unsubscribe).Return(&sns.UnsubscribeOutput{}, nil),
+		)
+		gomock.InOrder(
+			SQS.EXPECT().DeleteQueue(ctx, deleteQueue).Return(nil, &smithy.GenericAPIError{Code: "InternalError"}),
+			SQS.EXPECT().DeleteQueue(ctx, deleteQueue).Return(&sqs.DeleteQueueOutput{}, nil),
+		)
+		expectGone(ctx, SNS, SQS, "http://queue.url", "arn:foo:bar:subscription")
+
+		subscriber := newSubscriber(SNS, SQS)
+		assert.NoError(t, subscriber.Cleanup(ctx))
+	})
+
+	t.Run("removal is checked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		// subscription is still there, it's unsubscribed again, reported as already gone
+		gomock.InOrder(
+			SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(&sns.UnsubscribeOutput{}, nil),
+			SNS.EXPECT().GetSubscriptionAttributes(ctx, gomock.Any()).Return(&sns.GetSubscriptionAttributesOutput{}, nil),
+			SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(nil, &snstypes.NotFoundException{}),
+		)
+		SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(nil, &sqstypes.QueueDoesNotExist{})
+		expectGone(ctx, SNS, SQS, "http://queue.url", "arn:foo:bar:subscription")
+
+		subscriber := newSubscriber(SNS, SQS)
+		assert.NoError(t, subscriber.Cleanup(ctx))
+	})
+
+	t.Run("queue deletion in progress", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		// deleted queue is still visible for a while, it's neither deleted again nor leaked
+		SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(&sns.UnsubscribeOutput{}, nil)
+		SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(&sqs.DeleteQueueOutput{}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.Any()).Return(&sqs.GetQueueAttributesOutput{}, nil)
+		SNS.EXPECT().GetSubscriptionAttributes(ctx, gomock.Any()).Return(nil, &snstypes.NotFoundException{})
+
+		subscriber := newSubscriber(SNS, SQS)
+		start := time.Now()
+		assert.NoError(t, subscriber.Cleanup(ctx))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("removal not permitted to check", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(&sns.UnsubscribeOutput{}, nil)
+		SNS.EXPECT().GetSubscriptionAttributes(ctx, gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "AuthorizationError"})
+		SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(&sqs.DeleteQueueOutput{}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "AccessDenied"})
+
+		subscriber := newSubscriber(SNS, SQS)
+		assert.NoError(t, subscriber.Cleanup(ctx), "removed, unverified")
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(nil, throttled)
+		SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, _ *sqs.DeleteQueueInput, _ ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		subscriber := newSubscriber(SNS, SQS)
+		err := subscriber.Cleanup(ctx)
+
+		var leakErr *snstesting.LeakError
+		if assert.True(t, errors.As(err, &leakErr)) && assert.Len(t, leakErr.Leaks, 2) {
+			assert.Equal(t, "arn:foo:bar:subscription", leakErr.Leaks[0].SubscriptionARN)
+			assert.Contains(t, leakErr.Leaks[0].Reason, "Rate exceeded")
+			assert.Contains(t, leakErr.Leaks[0].Reason, context.DeadlineExceeded.Error())
+			assert.Equal(t, "http://queue.url", leakErr.Leaks[1].QueueURL)
+			assert.Equal(t, "TestOrders", leakErr.Leaks[1].TestName)
+			assert.Equal(t, "jenkins@ci", leakErr.Leaks[1].Owner)
+			assert.Nil(t, leakErr.Ledger)
+		}
+	})
+
+	t.Run("default owner", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders")
+		assert.NoError(t, err)
+		tags, err := backend.SQS().ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: aws.String(s.Config.QueueURL)})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, s.Config.Owner)
+		assert.Equal(t, tags.Tags[snstesting.TagOwner], s.Config.Owner, "leaks are reported with owner of the queue")
+	})
+
+	t.Run("leak ledger", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(nil, errors.New("foo")).Times(2)
+		gomock.InOrder(
+			SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(&sqs.DeleteQueueOutput{}, nil),
+			SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(nil, errors.New("bar")),
+		)
+		expectGone(ctx, SNS, SQS, "http://queue.url")
+
+		ledger := filepath.Join(t.TempDir(), "leaks.jsonl")
+		subscriber := newSubscriber(SNS, SQS)
+		subscriber.Config.LeakLedger = ledger
+		assert.EqualError(t, subscriber.Cleanup(ctx), "leaked subscription arn:foo:bar:subscription: foo")
+		assert.Error(t, subscriber.Cleanup(ctx), "ledger is appended")
+
+		leaks, err := snstesting.ReadLedger(ledger)
+		assert.NoError(t, err)
+		if assert.Len(t, leaks, 3) {
+			assert.Equal(t, "arn:foo:bar:subscription", leaks[0].SubscriptionARN)
+			assert.Equal(t, "foo", leaks[0].Reason)
+			assert.Equal(t, "TestOrders", leaks[0].TestName)
+			assert.Equal(t, "jenkins@ci", leaks[0].Owner)
+			assert.False(t, leaks[0].Time.IsZero())
+			assert.Equal(t, "http://queue.url", leaks[2].QueueURL)
+			assert.Equal(t, "bar", leaks[2].Reason)
+		}
+
+		subscriber.Config.LeakLedger = filepath.Join(t.TempDir(), "missing", "leaks.jsonl")
+		SNS.EXPECT().Unsubscribe(ctx, gomock.Any()).Return(nil, errors.New("foo"))
+		SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(nil, errors.New("bar"))
+		var leakErr *snstesting.LeakError
+		if assert.True(t, errors.As(subscriber.Cleanup(ctx), &leakErr)) {
+			assert.Error(t, leakErr.Ledger)
+		}
+	})
+}
+
+func TestSweeper_SweepLedger(t *testing.T) {
+	ctx := context.Background()
+
+	writeLedger := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "leaks.jsonl")
+		err := os.WriteFile(path, []byte(
+			`{"subscription_arn":"arn:foo:bar:orders:sub","reason":"foo","test":"TestOrders","time":"2023-03-10T12:00:00Z"}`+"\n"+
+				`{"queue_url":"http://queue.url","reason":"bar","test":"TestOrders","time":"2023-03-10T12:00:00Z"}`+"\n"), 0o644)
+		assert.NoError(t, err)
+		return path
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		sweeper := snstesting.Sweeper{SNS: mock.NewMockSNSAPI(ctrl), SQS: mock.NewMockSQSAPI(ctrl), DryRun: true}
+		path := writeLedger(t)
+
+		leaks, err := sweeper.SweepLedger(ctx, path)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Leak{
+			{SubscriptionARN: "arn:foo:bar:orders:sub", Reason: "foo", TestName: "TestOrders", Time: time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)},
+			{QueueURL: "http://queue.url", Reason: "bar", TestName: "TestOrders", Time: time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)},
+		}, leaks)
+
+		left, err := snstesting.ReadLedger(path)
+		assert.NoError(t, err)
+		assert.Equal(t, leaks, left, "ledger untouched")
+	})
+
+	t.Run("failures are kept", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		sweeper := snstesting.Sweeper{SNS: SNS, SQS: SQS}
+		path := writeLedger(t)
+
+		SNS.EXPECT().Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: aws.String("arn:foo:bar:orders:sub")}).
+			Return(nil, &snstypes.NotFoundException{})
+		SQS.EXPECT().DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: aws.String("http://queue.url")}).
+			Return(nil, errors.New("access denied"))
+		SNS.EXPECT().GetSubscriptionAttributes(ctx, gomock.Any()).Return(nil, &snstypes.NotFoundException{})
+
+		swept, err := sweeper.SweepLedger(ctx, path)
+		assert.EqualError(t, err, "sweep queue http://queue.url failure: access denied")
+		if assert.Len(t, swept, 1) {
+			assert.Equal(t, "arn:foo:bar:orders:sub", swept[0].SubscriptionARN)
+		}
+
+		left, err := snstesting.ReadLedger(path)
+		assert.NoError(t, err)
+		if assert.Len(t, left, 1) {
+			assert.Equal(t, "http://queue.url", left[0].QueueURL)
+		}
+
+		SQS.EXPECT().DeleteQueue(ctx, gomock.Any()).Return(&sqs.DeleteQueueOutput{}, nil)
+		expectGone(ctx, SNS, SQS, "http://queue.url")
+		swept, err = sweeper.SweepLedger(ctx, path)
+		assert.NoError(t, err)
+		assert.Len(t, swept, 1)
+
+		_, err = os.Stat(path)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "empty ledger removed")
+	})
+
+	t.Run("missing ledger", func(t *testing.T) {
+		leaks, err := snstesting.Sweeper{}.SweepLedger(ctx, filepath.Join(t.TempDir(), "leaks.jsonl"))
+		assert.NoError(t, err)
+		assert.Empty(t, leaks)
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/prozz/snstesting"
+)
+
+func ls(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "Usage: snstesting ls [flags]\n\nLists live ad-hoc queues and their subscriptions.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var aws awsFlags
+	aws.register(fs)
+	prefix := fs.String("prefix", "", "queue name prefix, 'snstesting_' by default")
+	olderThan := fs.Duration("older-than", 0, "list only queues older than given duration, e.g. 1h")
+
+	if positional, err := parseArgs(fs, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(positional, " "))
+	}
+
+	sweeper, err := newSweeper(ctx, aws, *prefix, stderr)
+	if err != nil {
+		return err
+	}
+	leftovers, err := sweeper.Leftovers(ctx, *olderThan)
+	if err != nil {
+		return err
+	}
+	return printLeftovers(stdout, leftovers, time.Now())
+}
+
+// newSweeper gives Sweeper of tagged queues only, queues matching the prefix but not created by snstesting are
+// reported to stderr, so a custom prefix can't remove queues of other applications.
+func newSweeper(ctx context.Context, aws awsFlags, prefix string, stderr io.Writer) (snstesting.Sweeper, error) {
+	cfg, err := aws.load(ctx)
+	if err != nil {
+		return snstesting.Sweeper{}, err
+	}
+	return snstesting.Sweeper{
+		SNS:             sns.NewFromConfig(cfg),
+		SQS:             sqs.NewFromConfig(cfg),
+		QueueNamePrefix: prefix,
+		Skipped: func(queueURL string) {
+			fmt.Fprintf(stderr, "skipped %s: not tagged by snstesting\n", queueName(queueURL))
+		},
+	}, nil
+}
+
+// printLeftovers writes a table of leftovers, one line per subscription, so topics are easy to grep for.
+func printLeftovers(w io.Writer, leftovers []snstesting.Leftover, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "QUEUE\tAGE\tOWNER\tTEST\tTOPIC\tSUBSCRIPTION")
+	for _, l := range leftovers {
+		row := fmt.Sprintf("%s\t%s\t%s\t%s", queueName(l.QueueURL), l.Age(now).Truncate(time.Second), orDash(l.Owner), orDash(l.TestName))
+		if len(l.Subscriptions) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t-\n", row)
+		}
+		for _, sub := range l.Subscriptions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", row, sub.TopicName, sub.SubscriptionARN)
+		}
+	}
+	return tw.Flush()
+}
+
+func queueName(queueURL string) string {
+	return queueURL[strings.LastIndex(queueURL, "/")+1:]
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func countSubscriptions(leftovers []snstesting.Leftover) int {
+	n := 0
+	for _, l := range leftovers {
+		n += len(l.Subscriptions)
+	}
+	return n
+}
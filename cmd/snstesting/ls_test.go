@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/prozz/snstesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintLeftovers(t *testing.T) {
+	now := time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)
+	leftovers := []snstesting.Leftover{
+		{
+			QueueURL:  "https://sqs.eu-west-1.amazonaws.com/123/snstesting_old",
+			CreatedAt: now.Add(-2*time.Hour - 500*time.Millisecond),
+			Owner:     "jenkins@ci",
+			TestName:  "TestOrders/created",
+			Subscriptions: []snstesting.Subscription{
+				{TopicName: "orders", SubscriptionARN: "arn:aws:sns:eu-west-1:123:orders:1"},
+				{TopicName: "payments", SubscriptionARN: "arn:aws:sns:eu-west-1:123:payments:1"},
+			},
+		},
+		{
+			QueueURL:  "https://sqs.eu-west-1.amazonaws.com/123/snstesting_fresh",
+			CreatedAt: now.Add(-time.Minute),
+		},
+	}
+
+	var buf bytes.Buffer
+	err := printLeftovers(&buf, leftovers, now)
+	assert.NoError(t, err)
+	assert.Equal(t, `QUEUE             AGE     OWNER       TEST                TOPIC     SUBSCRIPTION
+snstesting_old    2h0m0s  jenkins@ci  TestOrders/created  orders    arn:aws:sns:eu-west-1:123:orders:1
+snstesting_old    2h0m0s  jenkins@ci  TestOrders/created  payments  arn:aws:sns:eu-west-1:123:payments:1
+snstesting_fresh  1m0s    -           -                   -         -
+`, buf.String())
+	assert.Equal(t, 2, countSubscriptions(leftovers))
+}
@@ -0,0 +1,117 @@
+// Command snstesting watches SNS topics through ad-hoc SQS queues and cleans up after snstesting based tests.
+//
+// Usage:
+//
+//	snstesting tail [flags] <topic>
+//	snstesting ls [flags]
+//	snstesting sweep -older-than <duration> | -ledger <file> [-dry-run] [flags]
+//
+// AWS configuration is loaded the usual way, from environment and shared config files.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+const usage = `snstesting watches SNS topics through ad-hoc SQS queues.
+
+Usage:
+
+	snstesting <command> [flags] [arguments]
+
+Commands:
+
+	tail    stream messages published on a topic
+	ls      list ad-hoc queues and subscriptions left behind by tests
+	sweep   remove ad-hoc queues and subscriptions left behind by tests
+
+Run 'snstesting <command> -h' for command flags.
+`
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "snstesting: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errors.New("no command given")
+	}
+
+	switch args[0] {
+	case "tail":
+		return tail(ctx, args[1:], stdout, stderr)
+	case "ls":
+		return ls(ctx, args[1:], stdout, stderr)
+	case "sweep":
+		return sweep(ctx, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+// awsFlags are common flags of commands talking to AWS.
+type awsFlags struct {
+	profile  string
+	region   string
+	endpoint string
+}
+
+func (f *awsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.profile, "profile", "", "AWS shared config profile")
+	fs.StringVar(&f.region, "region", "", "AWS region")
+	fs.StringVar(&f.endpoint, "endpoint-url", "", "custom SNS and SQS endpoint, e.g. http://localhost:4566 for LocalStack")
+}
+
+func (f *awsFlags) load(ctx context.Context) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if f.profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(f.profile))
+	}
+	if f.region != "" {
+		opts = append(opts, config.WithRegion(f.region))
+	}
+	if f.endpoint != "" {
+		opts = append(opts, config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: f.endpoint, SigningRegion: region}, nil
+			})))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("load aws config failure: %v", err)
+	}
+	return cfg, nil
+}
+
+// parseArgs parses flags given before and after positional arguments, so 'tail orders -format raw' works too.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
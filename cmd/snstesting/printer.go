@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prozz/snstesting"
+)
+
+const (
+	formatPretty = "pretty"
+	formatRaw    = "raw"
+	formatJSONL  = "jsonl"
+)
+
+// printer writes received messages out in one of supported formats.
+type printer struct {
+	w         io.Writer
+	format    string
+	selectors []selector
+}
+
+func newPrinter(w io.Writer, format, selection string) (*printer, error) {
+	switch format {
+	case formatPretty, formatRaw, formatJSONL:
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+
+	var selectors []selector
+	if strings.TrimSpace(selection) != "" {
+		for _, expr := range strings.Split(selection, ",") {
+			sel, err := parseSelector(expr)
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, sel)
+		}
+	}
+	return &printer{w: w, format: format, selectors: selectors}, nil
+}
+
+// record is JSONL representation of the message.
+type record struct {
+	MessageID         string            `json:"messageId,omitempty"`
+	TopicARN          string            `json:"topicArn"`
+	Subject           string            `json:"subject,omitempty"`
+	Timestamp         time.Time         `json:"timestamp"`
+	MessageAttributes map[string]string `json:"messageAttributes,omitempty"`
+	Message           interface{}       `json:"message"`
+}
+
+func (p *printer) print(msg *snstesting.Message) error {
+	payload := p.selected(decodePayload(msg.Message))
+
+	var err error
+	switch p.format {
+	case formatRaw:
+		if p.selectors == nil {
+			_, err = fmt.Fprintln(p.w, msg.Message)
+			break
+		}
+		for _, v := range payload.([]interface{}) {
+			if _, err = fmt.Fprintln(p.w, rawValue(v)); err != nil {
+				break
+			}
+		}
+	case formatJSONL:
+		var b []byte
+		b, err = json.Marshal(record{
+			MessageID:         msg.MessageID,
+			TopicARN:          msg.TopicARN,
+			Subject:           msg.Subject,
+			Timestamp:         msg.Timestamp,
+			MessageAttributes: attributes(msg),
+			Message:           payload,
+		})
+		if err == nil {
+			_, err = fmt.Fprintf(p.w, "%s\n", b)
+		}
+	default:
+		err = p.pretty(msg, payload)
+	}
+	return err
+}
+
+func (p *printer) pretty(msg *snstesting.Message, payload interface{}) error {
+	header := fmt.Sprintf("--- %s %s", msg.Timestamp.Format(time.RFC3339Nano), msg.TopicARN)
+	if msg.Subject != "" {
+		header += fmt.Sprintf(" subject=%q", msg.Subject)
+	}
+	attrs := attributes(msg)
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header += fmt.Sprintf(" %s=%q", name, attrs[name])
+	}
+
+	b, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n%s\n", header, b)
+	return err
+}
+
+// selected gives payload or list of selected values, missing ones are null.
+func (p *printer) selected(payload interface{}) interface{} {
+	if p.selectors == nil {
+		return payload
+	}
+	values := make([]interface{}, len(p.selectors))
+	for i, sel := range p.selectors {
+		values[i], _ = sel.apply(payload)
+	}
+	if len(values) == 1 && p.format != formatRaw {
+		return values[0]
+	}
+	return values
+}
+
+// decodePayload parses JSON payload, anything else is kept as a string.
+func decodePayload(payload string) interface{} {
+	dec := json.NewDecoder(bytes.NewBufferString(payload))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return payload
+	}
+	return v
+}
+
+func attributes(msg *snstesting.Message) map[string]string {
+	if len(msg.MessageAttributes) == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, len(msg.MessageAttributes))
+	for name, attr := range msg.MessageAttributes {
+		attrs[name] = attr.Value
+	}
+	return attrs
+}
+
+// rawValue prints strings as is and everything else as JSON, just like 'jq -r'.
+func rawValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
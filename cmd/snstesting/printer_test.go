@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prozz/snstesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrinter(t *testing.T) {
+	msg := &snstesting.Message{
+		MessageID: "42",
+		TopicARN:  "arn:aws:sns:eu-west-1:123:orders",
+		Subject:   "created",
+		Message:   `{"id": "1", "total": 10.5, "items": ["a", "b"]}`,
+		Timestamp: time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC),
+		MessageAttributes: map[string]snstesting.MessageAttribute{
+			"event": {Type: "String", Value: "created"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		format    string
+		selection string
+		want      string
+	}{
+		{
+			name:   "pretty",
+			format: "pretty",
+			want: `--- 2023-03-10T12:00:00Z arn:aws:sns:eu-west-1:123:orders subject="created" event="created"
+{
+  "id": "1",
+  "items": [
+    "a",
+    "b"
+  ],
+  "total": 10.5
+}
+`,
+		},
+		{
+			name:      "pretty selection",
+			format:    "pretty",
+			selection: ".items",
+			want: `--- 2023-03-10T12:00:00Z arn:aws:sns:eu-west-1:123:orders subject="created" event="created"
+[
+  "a",
+  "b"
+]
+`,
+		},
+		{
+			name:   "raw",
+			format: "raw",
+			want:   `{"id": "1", "total": 10.5, "items": ["a", "b"]}` + "\n",
+		},
+		{
+			name:      "raw selection",
+			format:    "raw",
+			selection: ".id, .items, .missing",
+			want:      "1\n[\"a\",\"b\"]\nnull\n",
+		},
+		{
+			name:   "jsonl",
+			format: "jsonl",
+			want: `{"messageId":"42","topicArn":"arn:aws:sns:eu-west-1:123:orders","subject":"created","timestamp":"2023-03-10T12:00:00Z",` +
+				`"messageAttributes":{"event":"created"},"message":{"id":"1","items":["a","b"],"total":10.5}}` + "\n",
+		},
+		{
+			name:      "jsonl selection",
+			format:    "jsonl",
+			selection: ".total",
+			want: `{"messageId":"42","topicArn":"arn:aws:sns:eu-west-1:123:orders","subject":"created","timestamp":"2023-03-10T12:00:00Z",` +
+				`"messageAttributes":{"event":"created"},"message":10.5}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := newPrinter(&buf, tt.format, tt.selection)
+			assert.NoError(t, err)
+
+			assert.NoError(t, p.print(msg))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	t.Run("not a json payload", func(t *testing.T) {
+		var buf bytes.Buffer
+		p, err := newPrinter(&buf, "jsonl", "")
+		assert.NoError(t, err)
+
+		assert.NoError(t, p.print(&snstesting.Message{TopicARN: "arn", Message: "hello"}))
+		assert.Equal(t, `{"topicArn":"arn","timestamp":"0001-01-01T00:00:00Z","message":"hello"}`+"\n", buf.String())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := newPrinter(nil, "xml", "")
+		assert.EqualError(t, err, "unknown format: xml")
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("unknown command", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), []string{"foo"}, &stdout, &stderr)
+		assert.EqualError(t, err, "unknown command: foo")
+		assert.Contains(t, stderr.String(), "Usage:")
+	})
+
+	t.Run("tail without topic", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), []string{"tail", "-format", "raw"}, &stdout, &stderr)
+		assert.EqualError(t, err, "tail expects exactly one topic")
+	})
+
+	t.Run("tail with flags after topic", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), []string{"tail", "orders", "-format", "xml"}, &stdout, &stderr)
+		assert.EqualError(t, err, "unknown format: xml")
+	})
+}
+
+func TestRun_Sweep(t *testing.T) {
+	t.Run("without older than", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), []string{"sweep", "--dry-run"}, &stdout, &stderr)
+		assert.EqualError(t, err, "sweep requires positive -older-than or -ledger")
+	})
+
+	t.Run("unexpected arguments", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), []string{"ls", "orders"}, &stdout, &stderr)
+		assert.EqualError(t, err, "unexpected arguments: orders")
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// selector picks a value out of decoded JSON with jq-like path, e.g. '.order.items[0].id'.
+type selector struct {
+	steps []step
+}
+
+// step is either object key or array index.
+type step struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func parseSelector(expr string) (selector, error) {
+	expr = strings.TrimSpace(expr)
+	var sel selector
+	if !strings.HasPrefix(expr, ".") {
+		return sel, fmt.Errorf("invalid selector %q: has to start with '.'", expr)
+	}
+
+	rest := expr
+	for rest != "" && rest != "." {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return sel, fmt.Errorf("invalid selector %q: empty key", expr)
+			}
+			sel.steps = append(sel.steps, step{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return sel, fmt.Errorf("invalid selector %q: missing ']'", expr)
+			}
+			inside := rest[1:end]
+			if key, err := strconv.Unquote(inside); err == nil {
+				sel.steps = append(sel.steps, step{key: key})
+			} else {
+				i, err := strconv.Atoi(inside)
+				if err != nil {
+					return sel, fmt.Errorf("invalid selector %q: bad index %s", expr, inside)
+				}
+				sel.steps = append(sel.steps, step{index: i, isIdx: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return sel, fmt.Errorf("invalid selector %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return sel, nil
+}
+
+// apply follows the path, false is returned when some step can't be followed.
+// Negative indexes count from the end of an array.
+func (s selector) apply(v interface{}) (interface{}, bool) {
+	for _, st := range s.steps {
+		if st.isIdx {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			i := st.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, false
+			}
+			v = arr[i]
+			continue
+		}
+
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = obj[st.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{"order": {"id": "1", "items": [{"sku": "a"}, {"sku": "b"}], "odd key": true}}`), &doc)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		expr  string
+		want  interface{}
+		found bool
+	}{
+		{expr: ".", want: doc, found: true},
+		{expr: ".order.id", want: "1", found: true},
+		{expr: ".order.items[1].sku", want: "b", found: true},
+		{expr: ".order.items[-1].sku", want: "b", found: true},
+		{expr: `.order["odd key"]`, want: true, found: true},
+		{expr: ".order.items[2]", found: false},
+		{expr: ".order.id.more", found: false},
+		{expr: ".missing", found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sel, err := parseSelector(tt.expr)
+			assert.NoError(t, err)
+
+			got, found := sel.apply(doc)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseSelector("order")
+		assert.EqualError(t, err, `invalid selector "order": has to start with '.'`)
+		_, err = parseSelector(".order..id")
+		assert.EqualError(t, err, `invalid selector ".order..id": empty key`)
+		_, err = parseSelector(".items[0")
+		assert.EqualError(t, err, `invalid selector ".items[0": missing ']'`)
+		_, err = parseSelector(".items[x]")
+		assert.EqualError(t, err, `invalid selector ".items[x]": bad index x`)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+func sweep(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "Usage: snstesting sweep -older-than <duration> | -ledger <file> [flags]\n\nRemoves ad-hoc queues and their subscriptions left behind by killed test runs, or recorded as leaked by tests.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var aws awsFlags
+	aws.register(fs)
+	prefix := fs.String("prefix", "", "queue name prefix, 'snstesting_' by default; queues not tagged by snstesting are never removed")
+	olderThan := fs.Duration("older-than", 0, "remove only queues older than given duration, e.g. 1h (required)")
+	dryRun := fs.Bool("dry-run", false, "only report what would be removed")
+	ledger := fs.String("ledger", "", "also remove resources recorded in leak ledger file by tests, regardless of age")
+
+	if positional, err := parseArgs(fs, args); err != nil {
+		return err
+	} else if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(positional, " "))
+	}
+	if *olderThan <= 0 && *ledger == "" {
+		// resources of tests running right now must never be removed by accident
+		fs.Usage()
+		return errors.New("sweep requires positive -older-than or -ledger")
+	}
+
+	sweeper, err := newSweeper(ctx, aws, *prefix, stderr)
+	if err != nil {
+		return err
+	}
+	sweeper.DryRun = *dryRun
+
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+
+	var errs []error
+	if *ledger != "" {
+		leaks, err := sweeper.SweepLedger(ctx, *ledger)
+		for _, l := range leaks {
+			fmt.Fprintf(stdout, "%s\n", l)
+		}
+		fmt.Fprintf(stderr, "%s %d leaked resource(s) of ledger %s\n", verb, len(leaks), *ledger)
+		errs = append(errs, err)
+	}
+	if *olderThan > 0 {
+		swept, err := sweeper.Sweep(ctx, *olderThan)
+		if perr := printLeftovers(stdout, swept, time.Now()); perr != nil {
+			return perr
+		}
+		fmt.Fprintf(stderr, "%s %d queue(s) and %d subscription(s)\n", verb, len(swept), countSubscriptions(swept))
+		errs = append(errs, err)
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
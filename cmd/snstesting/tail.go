@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/prozz/snstesting"
+)
+
+// cleanupTimeout bounds the time spent removing ad-hoc resources after interruption.
+const cleanupTimeout = 30 * time.Second
+
+func tail(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "Usage: snstesting tail [flags] <topic>\n\nStreams messages published on the topic until interrupted.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var aws awsFlags
+	aws.register(fs)
+	format := fs.String("format", formatPretty, "output format: pretty, raw or jsonl")
+	selection := fs.String("select", "", "comma separated fields of the message payload to print, e.g. '.order.id, .items[0]'")
+	filterPolicy := fs.String("filter-policy", "", "filter policy of ad-hoc subscription, as JSON")
+	filterPolicyScope := fs.String("filter-policy-scope", "", "filter policy scope: MessageAttributes or MessageBody")
+	match := fs.String("match", "exact", "topic name matching: exact, glob or regexp")
+	raw := fs.Bool("raw-delivery", false, "enable raw message delivery on ad-hoc subscription")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("tail expects exactly one topic")
+	}
+
+	p, err := newPrinter(stdout, *format, *selection)
+	if err != nil {
+		return err
+	}
+
+	opts := []snstesting.Option{func(c *snstesting.Config) {
+		c.TestName = "snstesting tail"
+	}}
+	switch *match {
+	case "exact":
+	case "glob":
+		opts = append(opts, snstesting.WithTopicMatch(snstesting.MatchGlob))
+	case "regexp":
+		opts = append(opts, snstesting.WithTopicMatch(snstesting.MatchRegexp))
+	default:
+		return fmt.Errorf("unknown topic matching: %s", *match)
+	}
+	if *filterPolicy != "" {
+		opts = append(opts, snstesting.WithFilterPolicy(*filterPolicy))
+	}
+	if *filterPolicyScope != "" {
+		opts = append(opts, snstesting.WithFilterPolicyScope(*filterPolicyScope))
+	}
+	if *raw {
+		opts = append(opts, snstesting.WithRawMessageDelivery())
+	}
+
+	cfg, err := aws.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	// signals are caught during setup too, but only stop tailing once it's done, so setup is never cut in the middle
+	// and resources created so far are always cleaned up
+	tailCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s, err := snstesting.NewSubscriber(ctx, sns.NewFromConfig(cfg), sqs.NewFromConfig(cfg), positional[0], opts...)
+	if err != nil {
+		return err
+	}
+	if tailCtx.Err() == nil {
+		fmt.Fprintf(stderr, "tailing %s through %s, press Ctrl+C to stop\n", s.Config.TopicARN, s.Config.QueueURL)
+		err = stream(tailCtx, &s, p, stderr)
+	}
+
+	// original context is done by now, cleanup gets a fresh one
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if cerr := s.Cleanup(cleanupCtx); cerr != nil {
+		return fmt.Errorf("cleanup failure, run 'snstesting sweep' later: %v", cerr)
+	}
+	return err
+}
+
+// stream prints messages until ctx is done.
+func stream(ctx context.Context, s *snstesting.Subscriber, p *printer, stderr io.Writer) error {
+	st := s.Stream(ctx)
+	defer st.Stop()
+
+	errs := st.Errors()
+	for {
+		select {
+		case msg, ok := <-st.Messages():
+			if !ok {
+				return nil
+			}
+			if err := p.print(msg); err != nil {
+				return err
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			fmt.Fprintf(stderr, "receive failure: %v\n", err)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
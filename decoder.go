@@ -0,0 +1,127 @@
+package snstesting
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Decoder decodes message payload into v.
+// Decoders transforming payload, like Base64 or Gzip, accept *[]byte or *string as v, so they can be chained.
+type Decoder interface {
+	Decode(payload []byte, v interface{}) error
+}
+
+// DecoderFunc is an adapter to use ordinary functions as Decoder.
+type DecoderFunc func(payload []byte, v interface{}) error
+
+// Decode calls f(payload, v).
+func (f DecoderFunc) Decode(payload []byte, v interface{}) error {
+	return f(payload, v)
+}
+
+// Chain passes payload through all decoders in order, e.g. Chain(Base64(), Gzip(), JSON()).
+// All decoders but the last one have to transform payload, decoding it into []byte.
+func Chain(decoders ...Decoder) Decoder {
+	return DecoderFunc(func(payload []byte, v interface{}) error {
+		if len(decoders) == 0 {
+			return errors.New("empty decoder chain")
+		}
+		for _, d := range decoders[:len(decoders)-1] {
+			var next []byte
+			if err := d.Decode(payload, &next); err != nil {
+				return err
+			}
+			payload = next
+		}
+		return decoders[len(decoders)-1].Decode(payload, v)
+	})
+}
+
+// JSON decodes JSON payload, unknown fields and trailing data are errors. This is the default decoder.
+func JSON() Decoder {
+	return DecoderFunc(func(payload []byte, v interface{}) error {
+		dec := json.NewDecoder(bytes.NewReader(payload))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return err
+		}
+		if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+			return errors.New("unexpected data after JSON value")
+		}
+		return nil
+	})
+}
+
+// Base64 decodes standard base64 encoded payload.
+func Base64() Decoder {
+	return DecoderFunc(func(payload []byte, v interface{}) error {
+		b := make([]byte, base64.StdEncoding.DecodedLen(len(payload)))
+		n, err := base64.StdEncoding.Decode(b, payload)
+		if err != nil {
+			return fmt.Errorf("base64: %v", err)
+		}
+		return setBytes(b[:n], v)
+	})
+}
+
+// Gzip decompresses gzip compressed payload.
+func Gzip() Decoder {
+	return DecoderFunc(func(payload []byte, v interface{}) error {
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("gzip: %v", err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("gzip: %v", err)
+		}
+		return setBytes(b, v)
+	})
+}
+
+func setBytes(b []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *[]byte:
+		*v = b
+	case *string:
+		*v = string(b)
+	default:
+		return fmt.Errorf("can't decode into %T, use *[]byte or *string", v)
+	}
+	return nil
+}
+
+// Decode decodes message payload into v with Config.Decoder, JSON by default.
+// Message field of SNS notification is decoded, with raw message delivery that's the whole SQS message body.
+func (s *Subscriber) Decode(msg *Message, v interface{}) error {
+	return decode(s.decoder(), msg.Message, v)
+}
+
+func (s *Subscriber) decoder() Decoder {
+	if s.Config.Decoder == nil {
+		return JSON()
+	}
+	return s.Config.Decoder
+}
+
+func decode(d Decoder, payload string, v interface{}) error {
+	if err := d.Decode([]byte(payload), v); err != nil {
+		return fmt.Errorf("decode %s failure: %v", targetType(v), err)
+	}
+	return nil
+}
+
+// targetType names type being decoded into, so *order gives order.
+func targetType(v interface{}) string {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return fmt.Sprint(t)
+}
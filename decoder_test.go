@@ -0,0 +1,106 @@
+package snstesting_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/prozz/snstesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoders(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var v order
+		err := snstesting.JSON().Decode([]byte(`{"id": "1", "status": "paid"}`), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, order{ID: "1", Status: "paid"}, v)
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		var v string
+		err := snstesting.Base64().Decode([]byte(base64.StdEncoding.EncodeToString([]byte("hello"))), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", v)
+	})
+
+	t.Run("base64 failure", func(t *testing.T) {
+		var v []byte
+		err := snstesting.Base64().Decode([]byte("!!!"), &v)
+		assert.EqualError(t, err, "base64: illegal base64 data at input byte 0")
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		var v []byte
+		err := snstesting.Gzip().Decode(gzipped(t, "hello"), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), v)
+	})
+
+	t.Run("gzip into unsupported type", func(t *testing.T) {
+		var v order
+		err := snstesting.Gzip().Decode(gzipped(t, "hello"), &v)
+		assert.EqualError(t, err, "can't decode into *snstesting_test.order, use *[]byte or *string")
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		payload := base64.StdEncoding.EncodeToString(gzipped(t, `{"id": "1", "status": "paid"}`))
+
+		var v order
+		err := snstesting.Chain(snstesting.Base64(), snstesting.Gzip(), snstesting.JSON()).Decode([]byte(payload), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, order{ID: "1", Status: "paid"}, v)
+	})
+
+	t.Run("chain failure", func(t *testing.T) {
+		var v order
+		err := snstesting.Chain(snstesting.Base64(), snstesting.Gzip(), snstesting.JSON()).Decode([]byte("aGVsbG8="), &v)
+		assert.EqualError(t, err, "gzip: unexpected EOF")
+	})
+
+	t.Run("empty chain", func(t *testing.T) {
+		var v order
+		err := snstesting.Chain().Decode([]byte("{}"), &v)
+		assert.EqualError(t, err, "empty decoder chain")
+	})
+}
+
+func TestSubscriber_Decode(t *testing.T) {
+	t.Run("json by default", func(t *testing.T) {
+		s := snstesting.Subscriber{}
+
+		var v order
+		err := s.Decode(&snstesting.Message{Message: `{"id": "1", "status": "paid"}`}, &v)
+		assert.NoError(t, err)
+		assert.Equal(t, order{ID: "1", Status: "paid"}, v)
+	})
+
+	t.Run("configured decoder", func(t *testing.T) {
+		s := snstesting.Subscriber{Config: snstesting.Config{Decoder: snstesting.Chain(snstesting.Base64(), snstesting.JSON())}}
+
+		var v order
+		err := s.Decode(&snstesting.Message{Message: base64.StdEncoding.EncodeToString([]byte(`{"id": "1"}`))}, &v)
+		assert.NoError(t, err)
+		assert.Equal(t, order{ID: "1"}, v)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		s := snstesting.Subscriber{Config: snstesting.Config{Decoder: snstesting.Base64()}}
+
+		var v string
+		err := s.Decode(&snstesting.Message{Message: "!!!"}, &v)
+		assert.EqualError(t, err, "decode string failure: base64: illegal base64 data at input byte 0")
+	})
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,356 @@
+package snstesting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Cause of messages not arriving at ad-hoc queue, found by Diagnose.
+type Cause string
+
+const (
+	CauseTopicMissing        Cause = "topic does not exist"
+	CauseTopicPolicy         Cause = "topic policy denies publishing"
+	CauseTopicEncryption     Cause = "topic is encrypted with KMS key"
+	CauseSubscriptionMissing Cause = "subscription does not exist"
+	CauseSubscriptionPending Cause = "subscription is pending confirmation"
+	CauseFilterPolicy        Cause = "subscription has filter policy"
+	CauseQueueMissing        Cause = "queue does not exist"
+	CauseQueuePolicy         Cause = "queue policy does not let topic send messages"
+	CauseQueueEncryption     Cause = "queue is encrypted with KMS key"
+	// CauseInspectionFailed tells that resource could not be inspected, e.g. for lack of permissions.
+	CauseInspectionFailed Cause = "inspection failed"
+)
+
+// Finding is likely cause of messages not arriving at ad-hoc queue.
+type Finding struct {
+	Cause Cause
+	// Resource is ARN of topic or subscription, or URL of queue, the finding is about.
+	Resource string
+	// Detail explains the finding and tells how to fix it.
+	Detail string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Cause, f.Resource, f.Detail)
+}
+
+// Diagnosis lists likely causes of messages not arriving at ad-hoc queue, see Subscriber.Diagnose.
+type Diagnosis struct {
+	Findings []Finding
+}
+
+// Has tells if any of the findings is of given cause.
+func (d Diagnosis) Has(cause Cause) bool {
+	for _, f := range d.Findings {
+		if f.Cause == cause {
+			return true
+		}
+	}
+	return false
+}
+
+func (d Diagnosis) String() string {
+	if len(d.Findings) == 0 {
+		return "snstesting diagnosis: no delivery problems found, check if the message was published at all"
+	}
+	lines := []string{"snstesting diagnosis, likely causes of messages not arriving:"}
+	for _, f := range d.Findings {
+		lines = append(lines, "- "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diagnose inspects topics, ad-hoc subscriptions and queue for likely causes of messages not arriving:
+// restrictive topic or queue policy, KMS encryption, pending confirmation or filter policy.
+// Failures of single inspections are reported as findings, error is returned only when ctx is done.
+func (s *Subscriber) Diagnose(ctx context.Context) (Diagnosis, error) {
+	var d Diagnosis
+	subscriptions := s.Config.Subscriptions
+	if len(subscriptions) == 0 {
+		subscriptions = []Subscription{{TopicARN: s.Config.TopicARN, SubscriptionARN: s.Config.SubscriptionARN}}
+	}
+
+	var topicArns []string
+	for _, sub := range subscriptions {
+		d.Findings = append(d.Findings, s.diagnoseTopic(ctx, sub.TopicARN)...)
+		d.Findings = append(d.Findings, s.diagnoseSubscription(ctx, sub.SubscriptionARN)...)
+		topicArns = append(topicArns, sub.TopicARN)
+	}
+	d.Findings = append(d.Findings, s.diagnoseQueue(ctx, topicArns)...)
+	return d, ctx.Err()
+}
+
+func (s *Subscriber) diagnoseTopic(ctx context.Context, topicArn string) []Finding {
+	out, err := s.SNS.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{TopicArn: aws.String(topicArn)})
+	if isNotFound(err) {
+		return []Finding{{Cause: CauseTopicMissing, Resource: topicArn, Detail: "topic was removed, or it lives in other account or region"}}
+	}
+	if err != nil {
+		return []Finding{{Cause: CauseInspectionFailed, Resource: topicArn, Detail: fmt.Sprintf("get topic attributes failure: %v", err)}}
+	}
+
+	var findings []Finding
+	if keyID := out.Attributes["KmsMasterKeyId"]; keyID != "" {
+		if err := checkTopicKey(ctx, s.Config.KMS, keyID); err != nil {
+			findings = append(findings, Finding{Cause: CauseTopicEncryption, Resource: topicArn, Detail: fmt.Sprintf(
+				"%v, publishers need kms:GenerateDataKey* and kms:Decrypt permissions on key %s, publishing fails otherwise", err, keyID)})
+		}
+	}
+	if doc := out.Attributes["Policy"]; doc != "" {
+		p, err := parsePolicy(doc)
+		if err != nil {
+			return append(findings, Finding{Cause: CauseInspectionFailed, Resource: topicArn, Detail: fmt.Sprintf("parse topic policy failure: %v", err)})
+		}
+		for _, st := range p.Statement {
+			if st.Effect == "Deny" && st.Action.matches("sns:Publish") {
+				findings = append(findings, Finding{Cause: CauseTopicPolicy, Resource: topicArn, Detail: fmt.Sprintf(
+					"statement %q denies publishing to some principals or under some conditions", st.Sid)})
+			}
+		}
+	}
+	return findings
+}
+
+func (s *Subscriber) diagnoseSubscription(ctx context.Context, subscriptionARN string) []Finding {
+	out, err := s.SNS.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{SubscriptionArn: aws.String(subscriptionARN)})
+	if isNotFound(err) {
+		return []Finding{{Cause: CauseSubscriptionMissing, Resource: subscriptionARN, Detail: "subscription was removed, e.g. by cleanup or sweep"}}
+	}
+	if err != nil {
+		return []Finding{{Cause: CauseInspectionFailed, Resource: subscriptionARN, Detail: fmt.Sprintf("get subscription attributes failure: %v", err)}}
+	}
+
+	var findings []Finding
+	if out.Attributes["PendingConfirmation"] == "true" {
+		findings = append(findings, Finding{Cause: CauseSubscriptionPending, Resource: subscriptionARN, Detail: "nothing is delivered until subscription is confirmed, see WithReadinessProbe"})
+	}
+	if policy := out.Attributes["FilterPolicy"]; policy != "" {
+		scope := out.Attributes["FilterPolicyScope"]
+		if scope == "" {
+			scope = "MessageAttributes"
+		}
+		findings = append(findings, Finding{Cause: CauseFilterPolicy, Resource: subscriptionARN, Detail: fmt.Sprintf(
+			"messages not matching %s of %s are dropped", policy, scope)})
+	}
+	return findings
+}
+
+func (s *Subscriber) diagnoseQueue(ctx context.Context, topicArns []string) []Finding {
+	queueURL := s.Config.QueueURL
+	out, err := s.SQS.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameAll},
+	})
+	if isQueueGone(err) {
+		return []Finding{{Cause: CauseQueueMissing, Resource: queueURL, Detail: "queue was removed, e.g. by cleanup or sweep"}}
+	}
+	if err != nil {
+		return []Finding{{Cause: CauseInspectionFailed, Resource: queueURL, Detail: fmt.Sprintf("get queue attributes failure: %v", err)}}
+	}
+
+	var findings []Finding
+	if keyID := out.Attributes[string(types.QueueAttributeNameKmsMasterKeyId)]; keyID != "" {
+		detail := fmt.Sprintf("policy of key %s has to allow sns.amazonaws.com kms:GenerateDataKey* and kms:Decrypt, messages are dropped otherwise", keyID)
+		err := checkQueueKey(ctx, s.Config.KMS, keyID)
+		if err != nil {
+			detail = fmt.Sprintf("key %s: %v", keyID, err)
+		}
+		if err != nil || s.Config.KMS == nil {
+			findings = append(findings, Finding{Cause: CauseQueueEncryption, Resource: queueURL, Detail: detail})
+		}
+	}
+
+	doc := out.Attributes[string(types.QueueAttributeNamePolicy)]
+	p, err := parsePolicy(doc)
+	if err != nil {
+		return append(findings, Finding{Cause: CauseInspectionFailed, Resource: queueURL, Detail: fmt.Sprintf("parse queue policy failure: %v", err)})
+	}
+	for _, topicArn := range topicArns {
+		if allowed, sid := p.allowsSendMessage(topicArn); !allowed {
+			detail := "no statement allows sqs:SendMessage from " + topicArn
+			if sid != "" {
+				detail = fmt.Sprintf("statement %q denies sqs:SendMessage from %s", sid, topicArn)
+			}
+			findings = append(findings, Finding{Cause: CauseQueuePolicy, Resource: queueURL, Detail: detail})
+		}
+	}
+	return findings
+}
+
+// policy is the part of IAM resource policy needed to tell who may publish or send messages.
+type policy struct {
+	Statement statements
+}
+
+type statement struct {
+	Sid       string
+	Effect    string
+	Principal json.RawMessage
+	Action    stringOrList
+	Condition map[string]map[string]conditionValues
+}
+
+// statements are given as a list, or as single statement.
+type statements []statement
+
+func (s *statements) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '{' {
+		var st statement
+		err := json.Unmarshal(b, &st)
+		*s = statements{st}
+		return err
+	}
+	return json.Unmarshal(b, (*[]statement)(s))
+}
+
+// stringOrList is policy element given as single string or list of strings.
+type stringOrList []string
+
+func (v *stringOrList) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		err := json.Unmarshal(b, &s)
+		*v = stringOrList{s}
+		return err
+	}
+	return json.Unmarshal(b, (*[]string)(v))
+}
+
+// conditionValues are values of policy condition, given as single value or list. Booleans and numbers,
+// e.g. {"Bool": {"kms:GrantIsForAWSResource": true}}, are kept as strings, the way IAM compares them.
+type conditionValues []string
+
+func (v *conditionValues) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+	} else {
+		raw = []json.RawMessage{b}
+	}
+
+	values := make(conditionValues, 0, len(raw))
+	for _, r := range raw {
+		var s string
+		if len(r) > 0 && r[0] == '"' {
+			if err := json.Unmarshal(r, &s); err != nil {
+				return err
+			}
+			values = append(values, s)
+			continue
+		}
+		var scalar interface{}
+		if err := json.Unmarshal(r, &scalar); err != nil {
+			return err
+		}
+		switch scalar.(type) {
+		case bool, float64:
+			values = append(values, string(r))
+		default:
+			return fmt.Errorf("unsupported condition value %s", r)
+		}
+	}
+	*v = values
+	return nil
+}
+
+// matches tells if action, like 'sqs:SendMessage', is one of the actions, wildcards included. Case doesn't matter.
+func (v stringOrList) matches(action string) bool {
+	for _, pattern := range v {
+		if wildcardMatch(strings.ToLower(pattern), strings.ToLower(action)) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePolicy(s string) (policy, error) {
+	var p policy
+	if s == "" {
+		return p, nil
+	}
+	err := json.Unmarshal([]byte(s), &p)
+	return p, err
+}
+
+// allowsSendMessage tells if SNS may send messages from the topic according to queue policy.
+// When it's explicitly denied, Sid of denying statement is given. Conditions other than source ARN are assumed met.
+func (p policy) allowsSendMessage(topicArn string) (bool, string) {
+	allowed := false
+	for _, st := range p.Statement {
+		if !st.Action.matches("sqs:SendMessage") || !st.allowsSNS() || !st.matchesSource(topicArn) {
+			continue
+		}
+		switch st.Effect {
+		case "Deny":
+			return false, st.Sid
+		case "Allow":
+			allowed = true
+		}
+	}
+	return allowed, ""
+}
+
+// allowsSNS tells if statement principal covers SNS service.
+func (st statement) allowsSNS() bool {
+	var all string
+	if json.Unmarshal(st.Principal, &all) == nil {
+		return all == "*"
+	}
+	var principals map[string]stringOrList
+	if json.Unmarshal(st.Principal, &principals) != nil {
+		return false
+	}
+	for _, p := range principals["AWS"] {
+		if p == "*" {
+			return true
+		}
+	}
+	for _, p := range principals["Service"] {
+		if p == "sns.amazonaws.com" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSource tells if source ARN conditions of the statement, if any, match the topic.
+func (st statement) matchesSource(topicArn string) bool {
+	for operator, conditions := range st.Condition {
+		for key, values := range conditions {
+			if !strings.EqualFold(key, "aws:SourceArn") {
+				continue
+			}
+			like := strings.Contains(strings.ToLower(operator), "like")
+			matched := false
+			for _, v := range values {
+				if v == topicArn || (like && wildcardMatch(v, topicArn)) {
+					matched = true
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// wildcardMatch matches s against pattern with '*' and '?' wildcards, as used in policies.
+func wildcardMatch(pattern, s string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+expr+"$", s)
+	return matched
+}
@@ -0,0 +1,182 @@
+package snstesting_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriber_Diagnose(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nothing found", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+		backend.CreateTopic("payments")
+
+		s, err := snstesting.NewMultiSubscriber(ctx, backend.SNS(), backend.SQS(), []string{"orders", "payments"})
+		assert.NoError(t, err)
+
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, d.Findings)
+		assert.Equal(t, "snstesting diagnosis: no delivery problems found, check if the message was published at all", d.String())
+	})
+
+	t.Run("topic encryption and policy", func(t *testing.T) {
+		backend := fake.New()
+		_, err := backend.SNS().CreateTopic(ctx, &sns.CreateTopicInput{
+			Name: aws.String("orders"),
+			Attributes: map[string]string{
+				"KmsMasterKeyId": "alias/orders",
+				"Policy":         `{"Statement":{"Sid":"OnlyOrders","Effect":"Deny","Principal":"*","Action":"SNS:*","Condition":{"StringNotEquals":{"aws:PrincipalAccount":"123"}}}}`,
+			},
+		})
+		assert.NoError(t, err)
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithFilterPolicy(`{"event": ["created"]}`))
+		assert.NoError(t, err)
+
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Finding{
+			{Cause: snstesting.CauseTopicEncryption, Resource: s.Config.TopicARN, Detail: "key policy not inspected, see WithKMS, publishers need kms:GenerateDataKey* and kms:Decrypt permissions on key alias/orders, publishing fails otherwise"},
+			{Cause: snstesting.CauseTopicPolicy, Resource: s.Config.TopicARN, Detail: `statement "OnlyOrders" denies publishing to some principals or under some conditions`},
+			{Cause: snstesting.CauseFilterPolicy, Resource: s.Config.SubscriptionARN, Detail: `messages not matching {"event": ["created"]} of MessageAttributes are dropped`},
+		}, d.Findings)
+		assert.Contains(t, d.String(), "- subscription has filter policy: "+s.Config.SubscriptionARN)
+	})
+
+	t.Run("topic encryption checked with KMS", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		KMS := mock.NewMockKMSAPI(ctrl)
+		backend := fake.New()
+		_, err := backend.SNS().CreateTopic(ctx, &sns.CreateTopicInput{
+			Name:       aws.String("orders"),
+			Attributes: map[string]string{"KmsMasterKeyId": "alias/orders"},
+		})
+		assert.NoError(t, err)
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithKMS(KMS))
+		assert.NoError(t, err)
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled,
+			`{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"kms:*"}]}`)
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, d.Findings, "key usable by account principals")
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled,
+			`{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":["kms:Describe*","kms:Decrypt"]}]}`)
+		d, err = s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Finding{
+			{Cause: snstesting.CauseTopicEncryption, Resource: s.Config.TopicARN, Detail: "key policy does not grant kms:GenerateDataKey, publishers need kms:GenerateDataKey* and kms:Decrypt permissions on key alias/orders, publishing fails otherwise"},
+		}, d.Findings)
+	})
+
+	t.Run("queue encryption and policy", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders")
+		assert.NoError(t, err)
+		_, err = backend.SQS().SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl: aws.String(s.Config.QueueURL),
+			Attributes: map[string]string{
+				"KmsMasterKeyId": "alias/aws/sqs",
+				"Policy":         `{"Statement":[{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"sqs:SendMessage","Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:sns:us-east-1:123:other"}}}]}`,
+			},
+		})
+		assert.NoError(t, err)
+
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.True(t, d.Has(snstesting.CauseQueueEncryption))
+		assert.Contains(t, d.String(), "key alias/aws/sqs: SNS can't use AWS managed key")
+		assert.True(t, d.Has(snstesting.CauseQueuePolicy))
+		assert.Contains(t, d.String(), "no statement allows sqs:SendMessage from "+s.Config.TopicARN)
+
+		_, err = backend.SQS().SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl: aws.String(s.Config.QueueURL),
+			Attributes: map[string]string{
+				"KmsMasterKeyId": "alias/orders",
+				"Policy":         `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"SQS:*"},{"Sid":"NoSNS","Effect":"Deny","Principal":{"AWS":"*"},"Action":"sqs:Send*","Condition":{"ArnLike":{"aws:SourceArn":"arn:aws:sns:*"}}}]}`,
+			},
+		})
+		assert.NoError(t, err)
+
+		d, err = s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, d.String(), "policy of key alias/orders has to allow sns.amazonaws.com")
+		assert.Contains(t, d.String(), `statement "NoSNS" denies sqs:SendMessage from `+s.Config.TopicARN)
+	})
+
+	t.Run("pending confirmation", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, &probedSNS{SNS: backend.SNS(), pending: -1}, backend.SQS(), "orders")
+		assert.NoError(t, err)
+
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Finding{
+			{Cause: snstesting.CauseSubscriptionPending, Resource: s.Config.SubscriptionARN, Detail: "nothing is delivered until subscription is confirmed, see WithReadinessProbe"},
+		}, d.Findings)
+	})
+
+	t.Run("resources removed", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders")
+		assert.NoError(t, err)
+		assert.NoError(t, s.Cleanup(ctx))
+		_, err = backend.SNS().DeleteTopic(ctx, &sns.DeleteTopicInput{TopicArn: aws.String(s.Config.TopicARN)})
+		assert.NoError(t, err)
+
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.True(t, d.Has(snstesting.CauseTopicMissing))
+		assert.True(t, d.Has(snstesting.CauseSubscriptionMissing))
+		assert.True(t, d.Has(snstesting.CauseQueueMissing))
+		assert.False(t, d.Has(snstesting.CauseQueuePolicy))
+	})
+
+	t.Run("inspection failed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		SNS.EXPECT().GetTopicAttributes(ctx, gomock.Any()).Return(nil, errors.New("access denied"))
+		SNS.EXPECT().GetSubscriptionAttributes(ctx, gomock.Any()).Return(&sns.GetSubscriptionAttributesOutput{}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, _ *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+			cancel()
+			return nil, ctx.Err()
+		})
+
+		s := snstesting.Subscriber{SNS: SNS, SQS: SQS, Config: snstesting.Config{
+			TopicARN:        "arn:foo:bar",
+			SubscriptionARN: "arn:foo:bar:subscription",
+			QueueURL:        "http://queue.url",
+		}}
+		d, err := s.Diagnose(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []snstesting.Finding{
+			{Cause: snstesting.CauseInspectionFailed, Resource: "arn:foo:bar", Detail: "get topic attributes failure: access denied"},
+			{Cause: snstesting.CauseInspectionFailed, Resource: "http://queue.url", Detail: "get queue attributes failure: context canceled"},
+		}, d.Findings)
+	})
+}
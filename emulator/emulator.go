@@ -0,0 +1,170 @@
+// Package emulator runs SNS and SQS in-process, over HTTP, on top of the fake backend.
+// Real AWS SDK clients talk to it the same way they talk to AWS, so services under test publish
+// without any code changes, and no network or LocalStack container is needed:
+//
+//	cfg := emulator.Start(t)
+//
+//	// hand cfg to the service under test, it publishes on "orders" topic
+//	svc := orders.NewService(sns.NewFromConfig(cfg))
+//
+//	receive := snstesting.New(t, cfg, "orders")
+//	svc.PlaceOrder()
+//	assert.Contains(t, receive(), "order_placed")
+//
+// SNS is served with AWS Query protocol, SQS with both AWS Query and AWS JSON protocols,
+// for operations the fake backend supports. Requests are not authenticated, any credentials work.
+package emulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+)
+
+// Server is HTTP server emulating SNS and SQS, with state kept in the fake backend.
+type Server struct {
+	// URL of the server, endpoint of both services.
+	URL string
+	// Backend keeps topics, queues and messages, use it to set things up or peek at the state directly.
+	Backend *fake.Backend
+
+	srv    *httptest.Server
+	cancel context.CancelFunc
+	seq    int64
+}
+
+// Start runs emulator with empty backend for the duration of the test, and gives config of AWS clients talking to it.
+func Start(t snstesting.TB) aws.Config {
+	t.Helper()
+
+	s := NewServer(fake.New())
+	t.Cleanup(s.Close)
+	return s.Config()
+}
+
+// NewServer starts emulator on top of given backend, on local port. Close it when done.
+// Queue URLs of the backend are pointed at the server, unless its Endpoint is set already.
+func NewServer(backend *fake.Backend) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{Backend: backend, cancel: cancel}
+
+	s.srv = httptest.NewUnstartedServer(s)
+	// long polling requests end when server is closed, instead of keeping Close waiting
+	s.srv.Config.BaseContext = func(net.Listener) context.Context { return ctx }
+	s.srv.Start()
+
+	s.URL = s.srv.URL
+	if backend.Endpoint == "" {
+		backend.Endpoint = s.URL
+	}
+	return s
+}
+
+// Config gives config of AWS clients talking to the server, with static credentials and backend region.
+func (s *Server) Config() aws.Config {
+	return aws.Config{
+		Region: s.Backend.Region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "emulator", SecretAccessKey: "emulator", Source: "emulator"}, nil
+		}),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: s.URL, HostnameImmutable: true, SigningRegion: region}, nil
+		}),
+		HTTPClient: s.srv.Client(),
+	}
+}
+
+// Close stops the server, requests in progress are cancelled.
+func (s *Server) Close() {
+	s.cancel()
+	s.srv.Close()
+}
+
+// sqsTargetPrefix is X-Amz-Target header prefix of SQS requests in AWS JSON protocol.
+const sqsTargetPrefix = "AmazonSQS."
+
+// ServeHTTP serves SNS and SQS requests, telling services apart by protocol, credential scope or action name.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := s.requestID()
+
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		if !strings.HasPrefix(target, sqsTargetPrefix) {
+			writeJSONError(w, requestID, &queryError{code: "UnknownOperationException", message: "Unknown target " + target})
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSONError(w, requestID, err)
+			return
+		}
+		out, err := s.sqsJSON(r.Context(), strings.TrimPrefix(target, sqsTargetPrefix), body)
+		if err != nil {
+			writeJSONError(w, requestID, err)
+			return
+		}
+		writeJSON(w, requestID, out)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeQueryError(w, sqsNamespace, requestID, "MalformedQueryString", err.Error())
+		return
+	}
+	p := params{values: r.Form}
+	action := p.get("Action")
+
+	namespace, handle := sqsNamespace, s.sqsQuery
+	if service(r) == "sns" || (service(r) == "" && snsActions[action]) {
+		namespace, handle = snsNamespace, s.snsQuery
+	}
+
+	result, err := handle(r.Context(), action, p)
+	if err != nil {
+		code, message := errorCodeAndMessage(err)
+		writeQueryError(w, namespace, requestID, code, message)
+		return
+	}
+	writeQueryResponse(w, namespace, action, requestID, result)
+}
+
+// service gives name of the service request is signed for, empty when request is not signed.
+func service(r *http.Request) string {
+	// AWS4-HMAC-SHA256 Credential=<key>/<date>/<region>/<service>/aws4_request, SignedHeaders=..., Signature=...
+	auth := r.Header.Get("Authorization")
+	i := strings.Index(auth, "Credential=")
+	if i < 0 {
+		return ""
+	}
+	credential := auth[i+len("Credential="):]
+	if j := strings.Index(credential, ","); j >= 0 {
+		credential = credential[:j]
+	}
+	scope := strings.Split(credential, "/")
+	if len(scope) != 5 {
+		return ""
+	}
+	return scope[3]
+}
+
+func (s *Server) requestID() string {
+	return fmt.Sprintf("00000000-0000-4000-9000-%012d", atomic.AddInt64(&s.seq, 1))
+}
+
+func errorCodeAndMessage(err error) (string, string) {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode(), apiErr.ErrorMessage()
+	}
+	return "InternalFailure", err.Error()
+}
@@ -0,0 +1,309 @@
+package emulator_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/emulator"
+	"github.com/prozz/snstesting/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStart(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publish and receive with SDK clients", func(t *testing.T) {
+		cfg := emulator.Start(t)
+		snsClient := sns.NewFromConfig(cfg)
+
+		topic, err := snsClient.CreateTopic(ctx, &sns.CreateTopicInput{Name: aws.String("orders")})
+		assert.NoError(t, err)
+
+		receive := snstesting.NewMessageReceiver(t, cfg, "orders")
+
+		_, err = snsClient.Publish(ctx, &sns.PublishInput{
+			TopicArn: topic.TopicArn,
+			Subject:  aws.String("created"),
+			Message:  aws.String(`{"id": "1"}`),
+			MessageAttributes: map[string]snstypes.MessageAttributeValue{
+				"event": {DataType: aws.String("String"), StringValue: aws.String("order_created")},
+				"total": {DataType: aws.String("Number"), StringValue: aws.String("12.5")},
+			},
+		})
+		assert.NoError(t, err)
+
+		msg := receive()
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, aws.ToString(topic.TopicArn), msg.TopicARN)
+			assert.Equal(t, "created", msg.Subject)
+			assert.Equal(t, `{"id": "1"}`, msg.Message)
+			assert.Equal(t, "order_created", msg.MessageAttributes["event"].String())
+			assert.Equal(t, "12.5", msg.MessageAttributes["total"].String())
+		}
+	})
+
+	t.Run("readiness probe with canary", func(t *testing.T) {
+		cfg := emulator.Start(t)
+		snsClient := sns.NewFromConfig(cfg)
+
+		topic, err := snsClient.CreateTopic(ctx, &sns.CreateTopicInput{Name: aws.String("orders")})
+		assert.NoError(t, err)
+
+		receive := snstesting.NewMessageReceiver(t, cfg, "orders", snstesting.WithCanary())
+
+		_, err = snsClient.Publish(ctx, &sns.PublishInput{TopicArn: topic.TopicArn, Message: aws.String("hello")})
+		assert.NoError(t, err)
+
+		msg := receive()
+		if assert.NotNil(t, msg, "canary is never received") {
+			assert.Equal(t, "hello", msg.Message)
+		}
+	})
+
+	t.Run("raw delivery, batches and filter policy", func(t *testing.T) {
+		cfg := emulator.Start(t)
+		snsClient := sns.NewFromConfig(cfg)
+
+		topic, err := snsClient.CreateTopic(ctx, &sns.CreateTopicInput{Name: aws.String("orders")})
+		assert.NoError(t, err)
+
+		sub := snstesting.NewTestSubscriber(t, cfg, "orders",
+			snstesting.WithRawMessageDelivery(),
+			snstesting.WithFilterPolicy(snstesting.NewFilterPolicy().Equals("event", "created").String()),
+		)
+
+		event := func(v string) map[string]snstypes.MessageAttributeValue {
+			return map[string]snstypes.MessageAttributeValue{"event": {DataType: aws.String("String"), StringValue: aws.String(v)}}
+		}
+		out, err := snsClient.PublishBatch(ctx, &sns.PublishBatchInput{
+			TopicArn: topic.TopicArn,
+			PublishBatchRequestEntries: []snstypes.PublishBatchRequestEntry{
+				{Id: aws.String("1"), Message: aws.String("first"), MessageAttributes: event("created")},
+				{Id: aws.String("2"), Message: aws.String("second"), MessageAttributes: event("paid")},
+				{Id: aws.String("3"), Message: aws.String("third"), MessageAttributes: event("created")},
+				{Id: aws.String("4"), Message: aws.String("")},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, out.Successful, 3)
+		if assert.Len(t, out.Failed, 1) {
+			assert.Equal(t, "4", aws.ToString(out.Failed[0].Id))
+			assert.Equal(t, "InvalidParameter", aws.ToString(out.Failed[0].Code))
+		}
+
+		msgs := sub.ReceiveBatch(3)
+		if assert.Len(t, msgs, 2) {
+			assert.Equal(t, "first", msgs[0].Body)
+			assert.Equal(t, "third", msgs[1].Body)
+			assert.Equal(t, snstesting.MessageAttribute{Type: "String", Value: "created"}, msgs[1].MessageAttributes["event"])
+		}
+
+		attrs, err := snsClient.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{
+			SubscriptionArn: aws.String(sub.Subscriber.Config.SubscriptionARN),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "true", attrs.Attributes["RawMessageDelivery"])
+		assert.Equal(t, "false", attrs.Attributes["PendingConfirmation"])
+		assert.Equal(t, sub.Subscriber.Config.QueueARN, attrs.Attributes["Endpoint"])
+		assert.JSONEq(t, `{"event": ["created"]}`, attrs.Attributes["FilterPolicy"])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cfg := emulator.Start(t)
+
+		_, err := sns.NewFromConfig(cfg).Publish(ctx, &sns.PublishInput{
+			TopicArn: aws.String("arn:aws:sns:us-east-1:123456789012:missing"),
+			Message:  aws.String("hello"),
+		})
+		var notFound *snstypes.NotFoundException
+		assert.True(t, errors.As(err, &notFound), err)
+
+		_, err = sqs.NewFromConfig(cfg).GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("missing")})
+		var notExist *sqstypes.QueueDoesNotExist
+		assert.True(t, errors.As(err, &notExist), err)
+
+		_, err = sqs.NewFromConfig(cfg).ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String("http://missing")})
+		var apiErr smithy.APIError
+		if assert.True(t, errors.As(err, &apiErr), err) {
+			assert.Equal(t, "AWS.SimpleQueueService.NonExistentQueue", apiErr.ErrorCode())
+		}
+
+		_, err = snstesting.NewSubscriber(ctx, sns.NewFromConfig(cfg), sqs.NewFromConfig(cfg), "missing")
+		assert.EqualError(t, err, "topic missing not found")
+	})
+}
+
+func TestServer_SQS(t *testing.T) {
+	ctx := context.Background()
+
+	s := emulator.NewServer(fake.New())
+	defer s.Close()
+	client := sqs.NewFromConfig(s.Config())
+
+	q, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  aws.String("jobs"),
+		Attributes: map[string]string{"VisibilityTimeout": "60"},
+		Tags:       map[string]string{"team": "orders"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, s.URL+"/123456789012/jobs", aws.ToString(q.QueueUrl), "queue URLs point at the server")
+
+	url, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("jobs")})
+	assert.NoError(t, err)
+	assert.Equal(t, q.QueueUrl, url.QueueUrl)
+
+	sent, err := client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: q.QueueUrl,
+		Entries: []sqstypes.SendMessageBatchRequestEntry{
+			{Id: aws.String("a"), MessageBody: aws.String("first"), MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+				"blob": {DataType: aws.String("Binary"), BinaryValue: []byte{0, 1, 2}},
+			}},
+			{Id: aws.String("b"), MessageBody: aws.String("second")},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, sent.Successful, 2)
+
+	attrs, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       q.QueueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameVisibilityTimeout, sqstypes.QueueAttributeNameApproximateNumberOfMessages},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"VisibilityTimeout": "60", "ApproximateNumberOfMessages": "2"}, attrs.Attributes)
+
+	tags, err := client.ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: q.QueueUrl})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "orders"}, tags.Tags)
+
+	received, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              q.QueueUrl,
+		MaxNumberOfMessages:   10,
+		AttributeNames:        []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameAll},
+		MessageAttributeNames: []string{"All"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, received.Messages, 2) {
+		assert.Equal(t, "first", aws.ToString(received.Messages[0].Body))
+		assert.Equal(t, []byte{0, 1, 2}, received.Messages[0].MessageAttributes["blob"].BinaryValue)
+		assert.Equal(t, "1", received.Messages[0].Attributes["ApproximateReceiveCount"])
+	}
+
+	deleted, err := client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: q.QueueUrl,
+		Entries: []sqstypes.DeleteMessageBatchRequestEntry{
+			{Id: aws.String("a"), ReceiptHandle: received.Messages[0].ReceiptHandle},
+			{Id: aws.String("b"), ReceiptHandle: aws.String("invalid")},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, deleted.Successful, 1)
+	if assert.Len(t, deleted.Failed, 1) {
+		assert.Equal(t, "ReceiptHandleIsInvalid", aws.ToString(deleted.Failed[0].Code))
+	}
+
+	_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          q.QueueUrl,
+		ReceiptHandle:     received.Messages[1].ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second"}, s.Backend.Messages(aws.ToString(q.QueueUrl)))
+
+	queues, err := client.ListQueues(ctx, &sqs.ListQueuesInput{QueueNamePrefix: aws.String("jo")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{aws.ToString(q.QueueUrl)}, queues.QueueUrls)
+
+	_, err = client.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: q.QueueUrl})
+	assert.NoError(t, err)
+	assert.Empty(t, s.Backend.QueueURLs())
+}
+
+func TestServer_SQSJSON(t *testing.T) {
+	s := emulator.NewServer(fake.New())
+	defer s.Close()
+
+	call := func(operation string, in interface{}) (int, map[string]interface{}, http.Header) {
+		b, err := json.Marshal(in)
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, s.URL, strings.NewReader(string(b)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+		req.Header.Set("X-Amz-Target", "AmazonSQS."+operation)
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0, nil, nil
+		}
+		defer resp.Body.Close()
+
+		var out map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out, resp.Header
+	}
+
+	status, out, _ := call("CreateQueue", map[string]interface{}{"QueueName": "jobs"})
+	assert.Equal(t, http.StatusOK, status)
+	queueURL := out["QueueUrl"]
+	assert.Equal(t, s.URL+"/123456789012/jobs", queueURL)
+
+	status, out, _ = call("SendMessage", map[string]interface{}{
+		"QueueUrl":    queueURL,
+		"MessageBody": "hello",
+		"MessageAttributes": map[string]interface{}{
+			"event": map[string]string{"DataType": "String", "StringValue": "created"},
+		},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", out["MD5OfMessageBody"])
+
+	status, out, _ = call("ReceiveMessage", map[string]interface{}{"QueueUrl": queueURL, "MessageAttributeNames": []string{"All"}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotContains(t, out, "ResultMetadata")
+	msgs, _ := out["Messages"].([]interface{})
+	if assert.Len(t, msgs, 1) {
+		msg := msgs[0].(map[string]interface{})
+		assert.Equal(t, "hello", msg["Body"])
+		assert.Equal(t, map[string]interface{}{"DataType": "String", "StringValue": "created"},
+			msg["MessageAttributes"].(map[string]interface{})["event"].(map[string]interface{}))
+	}
+
+	status, out, header := call("DeleteQueue", map[string]interface{}{"QueueUrl": "http://missing"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "com.amazonaws.sqs#QueueDoesNotExist", out["__type"])
+	assert.Equal(t, "AWS.SimpleQueueService.NonExistentQueue;Sender", header.Get("X-Amzn-Query-Error"))
+
+	status, out, _ = call("PurgeQueue", map[string]interface{}{"QueueUrl": queueURL})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "com.amazonaws.sqs#UnknownOperationException", out["__type"])
+}
+
+func TestServer_Close(t *testing.T) {
+	ctx := context.Background()
+
+	s := emulator.NewServer(fake.New())
+	client := sqs.NewFromConfig(s.Config())
+	q, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("jobs")})
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.Close()
+	}()
+
+	start := time.Now()
+	_, err = client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: q.QueueUrl, WaitTimeSeconds: 20})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second, "long polling ends on close")
+}
@@ -0,0 +1,206 @@
+package emulator
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// params reads AWS Query protocol request parameters, like 'Attribute.1.Name' or 'Tags.member.2.Key'.
+type params struct {
+	values url.Values
+	// path of the node, empty for top level parameters
+	path string
+}
+
+func (p params) key(name string) string {
+	if p.path == "" {
+		return name
+	}
+	if name == "" {
+		return p.path
+	}
+	return p.path + "." + name
+}
+
+// get gives value of the parameter, empty when not given.
+func (p params) get(name string) string {
+	return p.values.Get(p.key(name))
+}
+
+// opt gives value of the parameter, nil when not given.
+func (p params) opt(name string) *string {
+	v, ok := p.values[p.key(name)]
+	if !ok || len(v) == 0 {
+		return nil
+	}
+	return &v[0]
+}
+
+func (p params) int32(name string) (int32, error) {
+	v := p.get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, invalidParameter(name, v)
+	}
+	return int32(n), nil
+}
+
+func (p params) optInt32(name string) (*int32, error) {
+	if p.opt(name) == nil {
+		return nil, nil
+	}
+	n, err := p.int32(name)
+	return &n, err
+}
+
+func (p params) bytes(name string) ([]byte, error) {
+	v := p.opt(name)
+	if v == nil {
+		return nil, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(*v)
+	if err != nil {
+		return nil, invalidParameter(name, *v)
+	}
+	return b, nil
+}
+
+// list gives members of the list parameter, both flattened ('Name.1') and wrapped ('Name.member.1', 'Name.entry.1').
+func (p params) list(name string) []params {
+	prefix := p.key(name) + "."
+	indexes := map[int]string{}
+	for k := range p.values {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(k, prefix)
+		wrapper := ""
+		for _, w := range []string{"member.", "entry."} {
+			if strings.HasPrefix(rest, w) {
+				wrapper, rest = w, strings.TrimPrefix(rest, w)
+				break
+			}
+		}
+		index := rest
+		if i := strings.Index(rest, "."); i >= 0 {
+			index = rest[:i]
+		}
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 1 {
+			continue
+		}
+		indexes[n] = prefix + wrapper + index
+	}
+
+	ns := make([]int, 0, len(indexes))
+	for n := range indexes {
+		ns = append(ns, n)
+	}
+	sort.Ints(ns)
+
+	members := make([]params, len(ns))
+	for i, n := range ns {
+		members[i] = params{values: p.values, path: indexes[n]}
+	}
+	return members
+}
+
+// strings gives values of the list parameter.
+func (p params) strings(name string) []string {
+	members := p.list(name)
+	if len(members) == 0 {
+		return nil
+	}
+	v := make([]string, len(members))
+	for i, m := range members {
+		v[i] = m.get("")
+	}
+	return v
+}
+
+// stringMap gives map parameter, with entries keyed and valued by given names.
+func (p params) stringMap(name, key, value string) map[string]string {
+	members := p.list(name)
+	if len(members) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(members))
+	for _, entry := range members {
+		m[entry.get(key)] = entry.get(value)
+	}
+	return m
+}
+
+// queryError is error of malformed request, reported the way services do.
+type queryError struct {
+	code    string
+	message string
+}
+
+func (e *queryError) Error() string                 { return e.code + ": " + e.message }
+func (e *queryError) ErrorCode() string             { return e.code }
+func (e *queryError) ErrorMessage() string          { return e.message }
+func (e *queryError) ErrorFault() smithy.ErrorFault { return smithy.FaultClient }
+
+func invalidParameter(name, value string) error {
+	return &queryError{code: "InvalidParameterValue", message: fmt.Sprintf("Value %s for parameter %s is invalid.", value, name)}
+}
+
+func invalidAction(action string) error {
+	return &queryError{code: "InvalidAction", message: fmt.Sprintf("The action %s is not valid for this endpoint.", action)}
+}
+
+// writeQueryResponse writes AWS Query protocol response, with result wrapped the same way services do.
+func writeQueryResponse(w http.ResponseWriter, namespace, action, requestID string, result interface{}) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.Header().Set("X-Amzn-RequestId", requestID)
+
+	enc := xml.NewEncoder(w)
+	root := xml.StartElement{Name: xml.Name{Local: action + "Response"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: namespace}}}
+	_ = enc.EncodeToken(root)
+	if result != nil {
+		_ = enc.EncodeElement(result, xml.StartElement{Name: xml.Name{Local: action + "Result"}})
+	}
+	_ = enc.EncodeElement(responseMetadata{RequestID: requestID}, xml.StartElement{Name: xml.Name{Local: "ResponseMetadata"}})
+	_ = enc.EncodeToken(root.End())
+	_ = enc.Flush()
+}
+
+type responseMetadata struct {
+	RequestID string `xml:"RequestId"`
+}
+
+// writeQueryError writes AWS Query protocol error response.
+func writeQueryError(w http.ResponseWriter, namespace, requestID string, code, message string) {
+	type errorDetail struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	type errorResponse struct {
+		XMLName   xml.Name    `xml:"ErrorResponse"`
+		Namespace string      `xml:"xmlns,attr"`
+		Error     errorDetail `xml:"Error"`
+		RequestID string      `xml:"RequestId"`
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.Header().Set("X-Amzn-RequestId", requestID)
+	w.WriteHeader(http.StatusBadRequest)
+	_ = xml.NewEncoder(w).Encode(errorResponse{
+		Namespace: namespace,
+		Error:     errorDetail{Type: "Sender", Code: code, Message: message},
+		RequestID: requestID,
+	})
+}
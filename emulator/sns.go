@@ -0,0 +1,205 @@
+package emulator
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// snsNamespace is XML namespace of SNS responses.
+const snsNamespace = "http://sns.amazonaws.com/doc/2010-03-31/"
+
+// snsActions are served by SNS, used to route requests that aren't signed.
+var snsActions = map[string]bool{
+	"CreateTopic":               true,
+	"DeleteTopic":               true,
+	"ListTopics":                true,
+	"GetTopicAttributes":        true,
+	"Subscribe":                 true,
+	"Unsubscribe":               true,
+	"GetSubscriptionAttributes": true,
+	"ListSubscriptions":         true,
+	"Publish":                   true,
+	"PublishBatch":              true,
+}
+
+type createTopicResult struct {
+	TopicArn *string
+}
+
+type listTopicsResult struct {
+	Topics    []snstypes.Topic `xml:"Topics>member"`
+	NextToken *string
+}
+
+type subscribeResult struct {
+	SubscriptionArn *string
+}
+
+// entry is key and value of map given in SNS response.
+type entry struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type getTopicAttributesResult struct {
+	Attributes []entry `xml:"Attributes>entry"`
+}
+
+type getSubscriptionAttributesResult struct {
+	Attributes []entry `xml:"Attributes>entry"`
+}
+
+type listSubscriptionsResult struct {
+	Subscriptions []snstypes.Subscription `xml:"Subscriptions>member"`
+	NextToken     *string
+}
+
+type publishResult struct {
+	MessageId      *string
+	SequenceNumber *string
+}
+
+type publishBatchResult struct {
+	Successful []snstypes.PublishBatchResultEntry `xml:"Successful>member"`
+	Failed     []snstypes.BatchResultErrorEntry   `xml:"Failed>member"`
+}
+
+// snsQuery serves SNS action given in AWS Query protocol, giving result to be wrapped in the response.
+func (s *Server) snsQuery(ctx context.Context, action string, p params) (interface{}, error) {
+	client := s.Backend.SNS()
+
+	switch action {
+	case "CreateTopic":
+		out, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
+			Name:       p.opt("Name"),
+			Attributes: p.stringMap("Attributes", "key", "value"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return createTopicResult{TopicArn: out.TopicArn}, nil
+
+	case "DeleteTopic":
+		_, err := client.DeleteTopic(ctx, &sns.DeleteTopicInput{TopicArn: p.opt("TopicArn")})
+		return nil, err
+
+	case "ListTopics":
+		out, err := client.ListTopics(ctx, &sns.ListTopicsInput{NextToken: p.opt("NextToken")})
+		if err != nil {
+			return nil, err
+		}
+		return listTopicsResult{Topics: out.Topics, NextToken: out.NextToken}, nil
+
+	case "GetTopicAttributes":
+		out, err := client.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{TopicArn: p.opt("TopicArn")})
+		if err != nil {
+			return nil, err
+		}
+		return getTopicAttributesResult{Attributes: mapEntries(out.Attributes)}, nil
+
+	case "Subscribe":
+		out, err := client.Subscribe(ctx, &sns.SubscribeInput{
+			TopicArn:              p.opt("TopicArn"),
+			Protocol:              p.opt("Protocol"),
+			Endpoint:              p.opt("Endpoint"),
+			Attributes:            p.stringMap("Attributes", "key", "value"),
+			ReturnSubscriptionArn: p.get("ReturnSubscriptionArn") == "true",
+		})
+		if err != nil {
+			return nil, err
+		}
+		return subscribeResult{SubscriptionArn: out.SubscriptionArn}, nil
+
+	case "Unsubscribe":
+		_, err := client.Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: p.opt("SubscriptionArn")})
+		return nil, err
+
+	case "GetSubscriptionAttributes":
+		out, err := client.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{SubscriptionArn: p.opt("SubscriptionArn")})
+		if err != nil {
+			return nil, err
+		}
+		return getSubscriptionAttributesResult{Attributes: mapEntries(out.Attributes)}, nil
+
+	case "ListSubscriptions":
+		out, err := client.ListSubscriptions(ctx, &sns.ListSubscriptionsInput{NextToken: p.opt("NextToken")})
+		if err != nil {
+			return nil, err
+		}
+		return listSubscriptionsResult{Subscriptions: out.Subscriptions, NextToken: out.NextToken}, nil
+
+	case "Publish":
+		attrs, err := snsMessageAttributes(p)
+		if err != nil {
+			return nil, err
+		}
+		out, err := client.Publish(ctx, &sns.PublishInput{
+			TopicArn:               p.opt("TopicArn"),
+			TargetArn:              p.opt("TargetArn"),
+			Message:                p.opt("Message"),
+			Subject:                p.opt("Subject"),
+			MessageStructure:       p.opt("MessageStructure"),
+			MessageAttributes:      attrs,
+			MessageGroupId:         p.opt("MessageGroupId"),
+			MessageDeduplicationId: p.opt("MessageDeduplicationId"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return publishResult{MessageId: out.MessageId, SequenceNumber: out.SequenceNumber}, nil
+
+	case "PublishBatch":
+		var entries []snstypes.PublishBatchRequestEntry
+		for _, e := range p.list("PublishBatchRequestEntries") {
+			attrs, err := snsMessageAttributes(e)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, snstypes.PublishBatchRequestEntry{
+				Id:                     e.opt("Id"),
+				Message:                e.opt("Message"),
+				Subject:                e.opt("Subject"),
+				MessageStructure:       e.opt("MessageStructure"),
+				MessageAttributes:      attrs,
+				MessageGroupId:         e.opt("MessageGroupId"),
+				MessageDeduplicationId: e.opt("MessageDeduplicationId"),
+			})
+		}
+		out, err := client.PublishBatch(ctx, &sns.PublishBatchInput{TopicArn: p.opt("TopicArn"), PublishBatchRequestEntries: entries})
+		if err != nil {
+			return nil, err
+		}
+		return publishBatchResult{Successful: out.Successful, Failed: out.Failed}, nil
+	}
+	return nil, invalidAction(action)
+}
+
+func snsMessageAttributes(p params) (map[string]snstypes.MessageAttributeValue, error) {
+	entries := p.list("MessageAttributes")
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	attrs := make(map[string]snstypes.MessageAttributeValue, len(entries))
+	for _, e := range entries {
+		binary, err := e.bytes("Value.BinaryValue")
+		if err != nil {
+			return nil, err
+		}
+		attrs[e.get("Name")] = snstypes.MessageAttributeValue{
+			DataType:    e.opt("Value.DataType"),
+			StringValue: e.opt("Value.StringValue"),
+			BinaryValue: binary,
+		}
+	}
+	return attrs, nil
+}
+
+func mapEntries(m map[string]string) []entry {
+	var e []entry
+	for _, k := range sortedKeys(m) {
+		e = append(e, entry{Key: k, Value: m[k]})
+	}
+	return e
+}
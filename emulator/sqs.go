@@ -0,0 +1,427 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// sqsNamespace is XML namespace of SQS responses.
+const sqsNamespace = "http://queue.amazonaws.com/doc/2012-11-05/"
+
+type attribute struct {
+	Name  string
+	Value string
+}
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+type queueURLResult struct {
+	QueueUrl *string
+}
+
+type getQueueAttributesResult struct {
+	Attributes []attribute `xml:"Attribute"`
+}
+
+type listQueuesResult struct {
+	QueueUrls []string `xml:"QueueUrl"`
+	NextToken *string
+}
+
+type listQueueTagsResult struct {
+	Tags []tag `xml:"Tag"`
+}
+
+type sendMessageResult struct {
+	MessageId        *string
+	MD5OfMessageBody *string
+	SequenceNumber   *string
+}
+
+type sendMessageBatchResult struct {
+	Successful []sqstypes.SendMessageBatchResultEntry `xml:"SendMessageBatchResultEntry"`
+	Failed     []sqstypes.BatchResultErrorEntry       `xml:"BatchResultErrorEntry"`
+}
+
+type receiveMessageResult struct {
+	Messages []receivedMessage `xml:"Message"`
+}
+
+type receivedMessage struct {
+	MessageId         *string
+	ReceiptHandle     *string
+	MD5OfBody         *string
+	Body              *string
+	Attributes        []attribute        `xml:"Attribute"`
+	MessageAttributes []messageAttribute `xml:"MessageAttribute"`
+}
+
+type messageAttribute struct {
+	Name  string
+	Value messageAttributeValue
+}
+
+type messageAttributeValue struct {
+	StringValue *string
+	BinaryValue *string
+	DataType    *string
+}
+
+type deleteMessageBatchResult struct {
+	Successful []sqstypes.DeleteMessageBatchResultEntry `xml:"DeleteMessageBatchResultEntry"`
+	Failed     []sqstypes.BatchResultErrorEntry         `xml:"BatchResultErrorEntry"`
+}
+
+// sqsQuery serves SQS action given in AWS Query protocol, giving result to be wrapped in the response.
+func (s *Server) sqsQuery(ctx context.Context, action string, p params) (interface{}, error) {
+	client := s.Backend.SQS()
+
+	switch action {
+	case "CreateQueue":
+		out, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+			QueueName:  p.opt("QueueName"),
+			Attributes: p.stringMap("Attribute", "Name", "Value"),
+			Tags:       p.stringMap("Tag", "Key", "Value"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return queueURLResult{QueueUrl: out.QueueUrl}, nil
+
+	case "GetQueueUrl":
+		out, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+			QueueName:              p.opt("QueueName"),
+			QueueOwnerAWSAccountId: p.opt("QueueOwnerAWSAccountId"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return queueURLResult{QueueUrl: out.QueueUrl}, nil
+
+	case "DeleteQueue":
+		_, err := client.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: p.opt("QueueUrl")})
+		return nil, err
+
+	case "GetQueueAttributes":
+		var names []sqstypes.QueueAttributeName
+		for _, name := range p.strings("AttributeName") {
+			names = append(names, sqstypes.QueueAttributeName(name))
+		}
+		out, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{QueueUrl: p.opt("QueueUrl"), AttributeNames: names})
+		if err != nil {
+			return nil, err
+		}
+		return getQueueAttributesResult{Attributes: attributes(out.Attributes)}, nil
+
+	case "SetQueueAttributes":
+		_, err := client.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl:   p.opt("QueueUrl"),
+			Attributes: p.stringMap("Attribute", "Name", "Value"),
+		})
+		return nil, err
+
+	case "ListQueues":
+		maxResults, err := p.optInt32("MaxResults")
+		if err != nil {
+			return nil, err
+		}
+		out, err := client.ListQueues(ctx, &sqs.ListQueuesInput{
+			QueueNamePrefix: p.opt("QueueNamePrefix"),
+			MaxResults:      maxResults,
+			NextToken:       p.opt("NextToken"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return listQueuesResult{QueueUrls: out.QueueUrls, NextToken: out.NextToken}, nil
+
+	case "ListQueueTags":
+		out, err := client.ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: p.opt("QueueUrl")})
+		if err != nil {
+			return nil, err
+		}
+		var tags []tag
+		for _, k := range sortedKeys(out.Tags) {
+			tags = append(tags, tag{Key: k, Value: out.Tags[k]})
+		}
+		return listQueueTagsResult{Tags: tags}, nil
+
+	case "SendMessage":
+		entry, err := sendMessageEntry(p)
+		if err != nil {
+			return nil, err
+		}
+		out, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:               p.opt("QueueUrl"),
+			MessageBody:            entry.MessageBody,
+			DelaySeconds:           entry.DelaySeconds,
+			MessageAttributes:      entry.MessageAttributes,
+			MessageGroupId:         entry.MessageGroupId,
+			MessageDeduplicationId: entry.MessageDeduplicationId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sendMessageResult{MessageId: out.MessageId, MD5OfMessageBody: out.MD5OfMessageBody, SequenceNumber: out.SequenceNumber}, nil
+
+	case "SendMessageBatch":
+		var entries []sqstypes.SendMessageBatchRequestEntry
+		for _, e := range p.list("SendMessageBatchRequestEntry") {
+			entry, err := sendMessageEntry(e)
+			if err != nil {
+				return nil, err
+			}
+			entry.Id = e.opt("Id")
+			entries = append(entries, entry)
+		}
+		out, err := client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{QueueUrl: p.opt("QueueUrl"), Entries: entries})
+		if err != nil {
+			return nil, err
+		}
+		return sendMessageBatchResult{Successful: out.Successful, Failed: out.Failed}, nil
+
+	case "ReceiveMessage":
+		in := &sqs.ReceiveMessageInput{
+			QueueUrl:              p.opt("QueueUrl"),
+			MessageAttributeNames: p.strings("MessageAttributeName"),
+		}
+		for _, name := range p.strings("AttributeName") {
+			in.AttributeNames = append(in.AttributeNames, sqstypes.QueueAttributeName(name))
+		}
+		var err error
+		if in.MaxNumberOfMessages, err = p.int32("MaxNumberOfMessages"); err != nil {
+			return nil, err
+		}
+		if in.VisibilityTimeout, err = p.int32("VisibilityTimeout"); err != nil {
+			return nil, err
+		}
+		if in.WaitTimeSeconds, err = p.int32("WaitTimeSeconds"); err != nil {
+			return nil, err
+		}
+		out, err := client.ReceiveMessage(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return receiveMessageResult{Messages: receivedMessages(out.Messages)}, nil
+
+	case "DeleteMessage":
+		_, err := client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: p.opt("QueueUrl"), ReceiptHandle: p.opt("ReceiptHandle")})
+		return nil, err
+
+	case "DeleteMessageBatch":
+		var entries []sqstypes.DeleteMessageBatchRequestEntry
+		for _, e := range p.list("DeleteMessageBatchRequestEntry") {
+			entries = append(entries, sqstypes.DeleteMessageBatchRequestEntry{Id: e.opt("Id"), ReceiptHandle: e.opt("ReceiptHandle")})
+		}
+		out, err := client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{QueueUrl: p.opt("QueueUrl"), Entries: entries})
+		if err != nil {
+			return nil, err
+		}
+		return deleteMessageBatchResult{Successful: out.Successful, Failed: out.Failed}, nil
+
+	case "ChangeMessageVisibility":
+		timeout, err := p.int32("VisibilityTimeout")
+		if err != nil {
+			return nil, err
+		}
+		_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          p.opt("QueueUrl"),
+			ReceiptHandle:     p.opt("ReceiptHandle"),
+			VisibilityTimeout: timeout,
+		})
+		return nil, err
+	}
+	return nil, invalidAction(action)
+}
+
+func sendMessageEntry(p params) (sqstypes.SendMessageBatchRequestEntry, error) {
+	delay, err := p.int32("DelaySeconds")
+	if err != nil {
+		return sqstypes.SendMessageBatchRequestEntry{}, err
+	}
+
+	var attrs map[string]sqstypes.MessageAttributeValue
+	for _, e := range p.list("MessageAttribute") {
+		binary, err := e.bytes("Value.BinaryValue")
+		if err != nil {
+			return sqstypes.SendMessageBatchRequestEntry{}, err
+		}
+		if attrs == nil {
+			attrs = map[string]sqstypes.MessageAttributeValue{}
+		}
+		attrs[e.get("Name")] = sqstypes.MessageAttributeValue{
+			DataType:    e.opt("Value.DataType"),
+			StringValue: e.opt("Value.StringValue"),
+			BinaryValue: binary,
+		}
+	}
+
+	return sqstypes.SendMessageBatchRequestEntry{
+		MessageBody:            p.opt("MessageBody"),
+		DelaySeconds:           delay,
+		MessageAttributes:      attrs,
+		MessageGroupId:         p.opt("MessageGroupId"),
+		MessageDeduplicationId: p.opt("MessageDeduplicationId"),
+	}, nil
+}
+
+func receivedMessages(msgs []sqstypes.Message) []receivedMessage {
+	out := make([]receivedMessage, len(msgs))
+	for i, m := range msgs {
+		out[i] = receivedMessage{
+			MessageId:     m.MessageId,
+			ReceiptHandle: m.ReceiptHandle,
+			MD5OfBody:     m.MD5OfBody,
+			Body:          m.Body,
+			Attributes:    attributes(m.Attributes),
+		}
+		for _, name := range sortedKeys(m.MessageAttributes) {
+			attr := m.MessageAttributes[name]
+			value := messageAttributeValue{StringValue: attr.StringValue, DataType: attr.DataType}
+			if attr.BinaryValue != nil {
+				value.BinaryValue = aws.String(base64.StdEncoding.EncodeToString(attr.BinaryValue))
+			}
+			out[i].MessageAttributes = append(out[i].MessageAttributes, messageAttribute{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+func attributes(m map[string]string) []attribute {
+	var attrs []attribute
+	for _, k := range sortedKeys(m) {
+		attrs = append(attrs, attribute{Name: k, Value: m[k]})
+	}
+	return attrs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sqsJSON serves SQS operation given in AWS JSON protocol, inputs and outputs have the same shape as SDK types.
+func (s *Server) sqsJSON(ctx context.Context, operation string, body []byte) (interface{}, error) {
+	client := s.Backend.SQS()
+
+	switch operation {
+	case "CreateQueue":
+		return call(ctx, body, client.CreateQueue)
+	case "GetQueueUrl":
+		return call(ctx, body, client.GetQueueUrl)
+	case "DeleteQueue":
+		return call(ctx, body, client.DeleteQueue)
+	case "GetQueueAttributes":
+		return call(ctx, body, client.GetQueueAttributes)
+	case "SetQueueAttributes":
+		return call(ctx, body, client.SetQueueAttributes)
+	case "ListQueues":
+		return call(ctx, body, client.ListQueues)
+	case "ListQueueTags":
+		return call(ctx, body, client.ListQueueTags)
+	case "SendMessage":
+		return call(ctx, body, client.SendMessage)
+	case "SendMessageBatch":
+		return call(ctx, body, client.SendMessageBatch)
+	case "ReceiveMessage":
+		return call(ctx, body, client.ReceiveMessage)
+	case "DeleteMessage":
+		return call(ctx, body, client.DeleteMessage)
+	case "DeleteMessageBatch":
+		return call(ctx, body, client.DeleteMessageBatch)
+	case "ChangeMessageVisibility":
+		return call(ctx, body, client.ChangeMessageVisibility)
+	}
+	return nil, &queryError{code: "UnknownOperationException", message: "Unknown operation " + operation}
+}
+
+// call decodes input of the operation from JSON body and calls it.
+func call[In, Out any](ctx context.Context, body []byte, operation func(context.Context, *In, ...func(*sqs.Options)) (*Out, error)) (interface{}, error) {
+	in := new(In)
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, in); err != nil {
+			return nil, &queryError{code: "SerializationException", message: err.Error()}
+		}
+	}
+	return operation(ctx, in)
+}
+
+// writeJSON writes AWS JSON protocol response, leaving out empty fields and SDK result metadata.
+func writeJSON(w http.ResponseWriter, requestID string, out interface{}) {
+	b, err := json.Marshal(out)
+	if err != nil {
+		writeJSONError(w, requestID, err)
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		writeJSONError(w, requestID, err)
+		return
+	}
+	delete(fields, "ResultMetadata")
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("X-Amzn-RequestId", requestID)
+	_ = json.NewEncoder(w).Encode(withoutNulls(fields))
+}
+
+// withoutNulls drops null fields of JSON document, SDK types give them for every unset pointer.
+func withoutNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if field == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = withoutNulls(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = withoutNulls(v[i])
+		}
+	}
+	return v
+}
+
+// jsonErrorShapes maps SQS query error codes to error shapes of AWS JSON protocol, where they differ.
+var jsonErrorShapes = map[string]string{
+	"AWS.SimpleQueueService.NonExistentQueue": "QueueDoesNotExist",
+	"QueueAlreadyExists":                      "QueueNameExists",
+}
+
+// writeJSONError writes AWS JSON protocol error, with query error code in a header for query compatible clients.
+func writeJSONError(w http.ResponseWriter, requestID string, err error) {
+	code, message := errorCodeAndMessage(err)
+	shape, ok := jsonErrorShapes[code]
+	if !ok {
+		shape = strings.TrimPrefix(code, "AWS.SimpleQueueService.")
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("X-Amzn-RequestId", requestID)
+	w.Header().Set("X-Amzn-Query-Error", code+";Sender")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"__type":  "com.amazonaws.sqs#" + shape,
+		"message": message,
+	})
+}
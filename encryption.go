@@ -0,0 +1,182 @@
+package snstesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// awsManagedKeyPrefix starts aliases of AWS managed keys, their key policies can't be changed to let SNS use them.
+const awsManagedKeyPrefix = "alias/aws/"
+
+// snsKeyActions have to be allowed to SNS by key policy of encrypted queue, messages are silently dropped otherwise.
+var snsKeyActions = []string{"kms:GenerateDataKey", "kms:Decrypt"}
+
+// checkEncryption resolves KMS key of the topics when the queue is to be encrypted with it, and checks that SNS
+// may use KMS key of the queue. Key policy is checked only with KMS client given, see WithKMS.
+func checkEncryption(ctx context.Context, SNS SNSAPI, topicArns []string, config *Config) error {
+	if config.TopicKMSKey {
+		keyID, err := topicKMSKey(ctx, SNS, topicArns)
+		if err != nil {
+			return err
+		}
+		config.KMSMasterKeyID = keyID
+	}
+	if config.KMSMasterKeyID == "" {
+		return nil
+	}
+	if config.KMS == nil && config.newKMS != nil {
+		config.KMS = config.newKMS()
+	}
+	if err := checkQueueKey(ctx, config.KMS, config.KMSMasterKeyID); err != nil {
+		return fmt.Errorf("KMS key %s can't encrypt ad-hoc queue: %v", config.KMSMasterKeyID, err)
+	}
+	return nil
+}
+
+// topicKMSKey gives KMS key the topics are encrypted with, all of them have to use the same key.
+func topicKMSKey(ctx context.Context, SNS SNSAPI, topicArns []string) (string, error) {
+	keys := map[string][]string{}
+	for _, topicArn := range topicArns {
+		out, err := SNS.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{TopicArn: aws.String(topicArn)})
+		if err != nil {
+			return "", fmt.Errorf("get topic attributes failure: %v", err)
+		}
+		keyID := out.Attributes["KmsMasterKeyId"]
+		if keyID == "" {
+			return "", fmt.Errorf("topic %s is not encrypted with KMS key", topicArn)
+		}
+		keys[keyID] = append(keys[keyID], topicArn)
+	}
+	if len(keys) > 1 {
+		var v []string
+		for keyID, arns := range keys {
+			v = append(v, fmt.Sprintf("%s (%s)", keyID, strings.Join(arns, ", ")))
+		}
+		sort.Strings(v)
+		return "", fmt.Errorf("topics are encrypted with different KMS keys: %s", strings.Join(v, ", "))
+	}
+	for keyID := range keys {
+		return keyID, nil
+	}
+	return "", nil
+}
+
+// checkQueueKey checks that SNS may encrypt messages for the queue with the key: the key is customer managed,
+// enabled, and its key policy allows SNS to use it. Missing permission to inspect the key is not an error.
+func checkQueueKey(ctx context.Context, KMS KMSAPI, keyID string) error {
+	if strings.HasPrefix(keyID, awsManagedKeyPrefix) {
+		return errors.New("SNS can't use AWS managed key, use customer managed key or WithSSE")
+	}
+	if KMS == nil {
+		return nil
+	}
+
+	out, err := KMS.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if isAuthError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("describe key failure: %v", err)
+	}
+	key := out.KeyMetadata
+	if key.KeyManager == kmstypes.KeyManagerTypeAws {
+		return errors.New("SNS can't use AWS managed key, use customer managed key or WithSSE")
+	}
+	if key.KeyState != kmstypes.KeyStateEnabled {
+		return fmt.Errorf("key is %s", key.KeyState)
+	}
+
+	policyOut, err := KMS.GetKeyPolicy(ctx, &kms.GetKeyPolicyInput{KeyId: key.KeyId, PolicyName: aws.String("default")})
+	if isAuthError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get key policy failure: %v", err)
+	}
+	p, err := parsePolicy(aws.ToString(policyOut.Policy))
+	if err != nil {
+		return fmt.Errorf("parse key policy failure: %v", err)
+	}
+	if missing := p.deniedToSNS(snsKeyActions); len(missing) > 0 {
+		return fmt.Errorf("key policy does not allow sns.amazonaws.com %s, messages would be silently dropped",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkTopicKey checks that publishers may encrypt messages for the topic with the key: the key is enabled,
+// and its key policy grants kms:GenerateDataKey* and kms:Decrypt to any principal, e.g. to the account for IAM
+// policies to take over. AWS managed keys are usable by publishers of the account. Key not inspected is an error.
+func checkTopicKey(ctx context.Context, KMS KMSAPI, keyID string) error {
+	if strings.HasPrefix(keyID, awsManagedKeyPrefix) {
+		return nil
+	}
+	if KMS == nil {
+		return errors.New("key policy not inspected, see WithKMS")
+	}
+
+	out, err := KMS.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return fmt.Errorf("describe key failure: %v", err)
+	}
+	key := out.KeyMetadata
+	if key.KeyManager == kmstypes.KeyManagerTypeAws {
+		return nil
+	}
+	if key.KeyState != kmstypes.KeyStateEnabled {
+		return fmt.Errorf("key is %s", key.KeyState)
+	}
+
+	policyOut, err := KMS.GetKeyPolicy(ctx, &kms.GetKeyPolicyInput{KeyId: key.KeyId, PolicyName: aws.String("default")})
+	if err != nil {
+		return fmt.Errorf("get key policy failure: %v", err)
+	}
+	p, err := parsePolicy(aws.ToString(policyOut.Policy))
+	if err != nil {
+		return fmt.Errorf("parse key policy failure: %v", err)
+	}
+	anyone := func(statement) bool { return true }
+	if missing := p.denied(snsKeyActions, anyone); len(missing) > 0 {
+		return fmt.Errorf("key policy does not grant %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// deniedToSNS gives actions the policy doesn't allow to SNS, see denied.
+func (p policy) deniedToSNS(actions []string) []string {
+	return p.denied(actions, statement.allowsSNS)
+}
+
+// denied gives actions the policy doesn't allow to principals matched by principal. Conditions of Allow statements
+// are assumed met, Deny statements with conditions are skipped, as they commonly guard other callers,
+// e.g. with aws:SecureTransport.
+func (p policy) denied(actions []string, principal func(statement) bool) []string {
+	var denied []string
+	for _, action := range actions {
+		allowed := false
+		for _, st := range p.Statement {
+			if !st.Action.matches(action) || !principal(st) {
+				continue
+			}
+			if st.Effect == "Deny" && len(st.Condition) == 0 {
+				allowed = false
+				break
+			}
+			if st.Effect == "Allow" {
+				allowed = true
+			}
+		}
+		if !allowed {
+			denied = append(denied, action)
+		}
+	}
+	return denied
+}
@@ -0,0 +1,159 @@
+package snstesting_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectKey expects KMS key to be inspected, giving its state and key policy.
+func expectKey(KMS *mock.MockKMSAPI, keyID string, manager kmstypes.KeyManagerType, state kmstypes.KeyState, policy string) {
+	KMS.EXPECT().DescribeKey(gomock.Any(), &kms.DescribeKeyInput{KeyId: aws.String(keyID)}).Return(&kms.DescribeKeyOutput{
+		KeyMetadata: &kmstypes.KeyMetadata{KeyId: aws.String("1234abcd"), KeyManager: manager, KeyState: state},
+	}, nil)
+	if policy != "" {
+		KMS.EXPECT().GetKeyPolicy(gomock.Any(), &kms.GetKeyPolicyInput{KeyId: aws.String("1234abcd"), PolicyName: aws.String("default")}).
+			Return(&kms.GetKeyPolicyOutput{Policy: aws.String(policy)}, nil)
+	}
+}
+
+func queueAttribute(t *testing.T, backend *fake.Backend, queueURL string, name sqstypes.QueueAttributeName) string {
+	t.Helper()
+
+	out, err := backend.SQS().GetQueueAttributes(context.Background(), &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{name},
+	})
+	assert.NoError(t, err)
+	return out.Attributes[string(name)]
+}
+
+func TestNewSubscriber_Encryption(t *testing.T) {
+	ctx := context.Background()
+	rootOnly := `{"Statement":[{"Sid":"Enable IAM User Permissions","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"kms:*","Resource":"*"}]}`
+	allowSNS := `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"kms:*","Resource":"*"},` +
+		`{"Sid":"AllowSNS","Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":["kms:GenerateDataKey*","kms:Decrypt"],"Resource":"*"}]}`
+
+	createEncryptedTopic := func(t *testing.T, backend *fake.Backend, name, keyID string) {
+		_, err := backend.SNS().CreateTopic(ctx, &sns.CreateTopicInput{
+			Name:       aws.String(name),
+			Attributes: map[string]string{"KmsMasterKeyId": keyID},
+		})
+		assert.NoError(t, err)
+	}
+
+	t.Run("SSE", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithSSE())
+		assert.NoError(t, err)
+		assert.Equal(t, "true", queueAttribute(t, backend, s.Config.QueueURL, sqstypes.QueueAttributeNameSqsManagedSseEnabled))
+
+		_, err = snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders",
+			snstesting.WithSSE(), snstesting.WithKMSMasterKey("alias/orders"))
+		assert.EqualError(t, err, "SSE-SQS and KMS key can't be used together, pick one of them")
+	})
+
+	t.Run("topic KMS key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		KMS := mock.NewMockKMSAPI(ctrl)
+		backend := fake.New()
+		createEncryptedTopic(t, backend, "orders", "alias/orders")
+		createEncryptedTopic(t, backend, "payments", "alias/orders")
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled, allowSNS)
+		s, err := snstesting.NewMultiSubscriber(ctx, backend.SNS(), backend.SQS(), []string{"orders", "payments"},
+			snstesting.WithTopicKMSKey(), snstesting.WithKMS(KMS))
+		assert.NoError(t, err)
+		assert.Equal(t, "alias/orders", s.Config.KMSMasterKeyID)
+		assert.Equal(t, "alias/orders", queueAttribute(t, backend, s.Config.QueueURL, sqstypes.QueueAttributeNameKmsMasterKeyId))
+	})
+
+	t.Run("topic KMS key missing", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+		createEncryptedTopic(t, backend, "payments", "alias/payments")
+		createEncryptedTopic(t, backend, "refunds", "alias/orders")
+
+		_, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithTopicKMSKey())
+		assert.EqualError(t, err, "topic "+topicArn+" is not encrypted with KMS key")
+
+		_, err = snstesting.NewMultiSubscriber(ctx, backend.SNS(), backend.SQS(), []string{"payments", "refunds"}, snstesting.WithTopicKMSKey())
+		assert.ErrorContains(t, err, "topics are encrypted with different KMS keys: alias/orders (")
+		assert.Empty(t, backend.QueueURLs())
+	})
+
+	t.Run("AWS managed key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		KMS := mock.NewMockKMSAPI(ctrl)
+		backend := fake.New()
+		createEncryptedTopic(t, backend, "orders", "alias/aws/sns")
+
+		_, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithTopicKMSKey())
+		assert.EqualError(t, err, "KMS key alias/aws/sns can't encrypt ad-hoc queue: SNS can't use AWS managed key, use customer managed key or WithSSE")
+
+		keyArn := "arn:aws:kms:us-east-1:123456789012:key/1234abcd"
+		expectKey(KMS, keyArn, kmstypes.KeyManagerTypeAws, kmstypes.KeyStateEnabled, "")
+		_, err = snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithKMSMasterKey(keyArn), snstesting.WithKMS(KMS))
+		assert.ErrorContains(t, err, "SNS can't use AWS managed key")
+		assert.Empty(t, backend.QueueURLs())
+	})
+
+	t.Run("key policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		KMS := mock.NewMockKMSAPI(ctrl)
+		backend := fake.New()
+		backend.CreateTopic("orders")
+		withKey := []snstesting.Option{snstesting.WithKMSMasterKey("alias/orders"), snstesting.WithKMS(KMS)}
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled, rootOnly)
+		_, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", withKey...)
+		assert.EqualError(t, err, "KMS key alias/orders can't encrypt ad-hoc queue: key policy does not allow sns.amazonaws.com kms:GenerateDataKey, kms:Decrypt, messages would be silently dropped")
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled,
+			`{"Statement":[{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"kms:*"},{"Effect":"Deny","Principal":"*","Action":"kms:Decrypt"}]}`)
+		_, err = snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", withKey...)
+		assert.ErrorContains(t, err, "key policy does not allow sns.amazonaws.com kms:Decrypt,")
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled,
+			`{"Statement":[{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"kms:*"},`+
+				`{"Sid":"DenyInsecure","Effect":"Deny","Principal":"*","Action":"kms:*","Condition":{"Bool":{"aws:SecureTransport":"false"}}}]}`)
+		_, err = snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", withKey...)
+		assert.NoError(t, err, "conditional deny is not assumed to apply")
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled,
+			`{"Statement":[{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"kms:*"},`+
+				`{"Effect":"Allow","Principal":{"AWS":"*"},"Action":"kms:CreateGrant","Condition":{"Bool":{"kms:GrantIsForAWSResource":true}}},`+
+				`{"Effect":"Deny","Principal":"*","Action":"kms:*","Condition":{"NumericLessThan":{"kms:RecipientAttestation:Version":[1, 2.5]}}}]}`)
+		_, err = snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", withKey...)
+		assert.NoError(t, err, "bool and number condition values")
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStatePendingDeletion, "")
+		_, err = snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", withKey...)
+		assert.EqualError(t, err, "KMS key alias/orders can't encrypt ad-hoc queue: key is PendingDeletion")
+		assert.Len(t, backend.QueueURLs(), 2)
+
+		// lack of permissions to inspect the key doesn't stop the test
+		KMS.EXPECT().DescribeKey(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "AccessDeniedException"})
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", withKey...)
+		assert.NoError(t, err)
+
+		expectKey(KMS, "alias/orders", kmstypes.KeyManagerTypeCustomer, kmstypes.KeyStateEnabled, allowSNS)
+		d, err := s.Diagnose(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, d.Findings, "key checked by diagnosis")
+	})
+}
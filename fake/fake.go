@@ -0,0 +1,132 @@
+// Package fake provides stateful in-memory SNS and SQS, implementing snstesting.SNSAPI and snstesting.SQSAPI.
+// It lets the whole subscribe, publish and receive flow run offline in unit tests:
+//
+//	backend := fake.New()
+//	topicArn := backend.CreateTopic("orders")
+//
+//	s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders")
+//	...
+//	_, err = backend.SNS().Publish(ctx, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("hello")})
+//	...
+//	msg, err := s.ReceiveMessage(ctx)
+//
+// Topics, queues, subscriptions, queue policies, filter policies and visibility timeouts are kept, and published
+// messages fan out to subscribed queues the same way real SNS does. Anything else, like IAM or KMS, is out of scope.
+package fake
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// DefaultRegion of fake resources.
+	DefaultRegion = "us-east-1"
+	// DefaultAccountID of fake resources.
+	DefaultAccountID = "123456789012"
+)
+
+// Backend keeps state shared by fake SNS and SQS.
+type Backend struct {
+	// Region and AccountID are used in ARNs and URLs of created resources.
+	Region    string
+	AccountID string
+	// Endpoint is base of queue URLs, https://sqs.<region>.amazonaws.com when empty.
+	Endpoint string
+
+	mu            sync.Mutex
+	topics        map[string]*topic
+	topicOrder    []string
+	queues        map[string]*queue
+	queueOrder    []string
+	subscriptions map[string]*subscription
+	subOrder      []string
+	seq           int64
+}
+
+// New creates empty Backend.
+func New() *Backend {
+	return &Backend{
+		Region:        DefaultRegion,
+		AccountID:     DefaultAccountID,
+		topics:        map[string]*topic{},
+		queues:        map[string]*queue{},
+		subscriptions: map[string]*subscription{},
+	}
+}
+
+// SNS gives fake SNS client of the backend.
+func (b *Backend) SNS() *SNS {
+	return &SNS{b: b}
+}
+
+// SQS gives fake SQS client of the backend.
+func (b *Backend) SQS() *SQS {
+	return &SQS{b: b}
+}
+
+// CreateTopic creates topic with given name, FIFO when name ends with '.fifo', and returns its ARN.
+// Creating the same topic again is a no-op. Panics on invalid topic name.
+func (b *Backend) CreateTopic(name string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var attrs map[string]string
+	if strings.HasSuffix(name, ".fifo") {
+		attrs = map[string]string{"FifoTopic": "true", "ContentBasedDeduplication": "true"}
+	}
+	t, err := b.createTopic(name, attrs)
+	if err != nil {
+		panic(err)
+	}
+	return t.arn
+}
+
+// Messages gives bodies of all messages currently in the queue, visible or not, in order of arrival.
+// Handy for assertions that shouldn't change the state of the queue.
+func (b *Backend) Messages(queueURL string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueURL]
+	if !ok {
+		return nil
+	}
+	bodies := make([]string, len(q.msgs))
+	for i, m := range q.msgs {
+		bodies[i] = m.body
+	}
+	return bodies
+}
+
+// nextID gives unique, UUID looking identifier.
+func (b *Backend) nextID() string {
+	b.seq++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", b.seq)
+}
+
+func (b *Backend) arn(service, resource string) string {
+	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, b.Region, b.AccountID, resource)
+}
+
+// apiError builds error the same way SDK deserializes service errors, with code and message.
+func apiError(code, format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient}
+}
+
+// errorCodeAndMessage gives error code and message the way batch operations report failures of entries.
+func errorCodeAndMessage(err error) (string, string) {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode(), apiErr.ErrorMessage()
+	}
+	return "InternalError", err.Error()
+}
+
+func lastSegment(s, sep string) string {
+	return s[strings.LastIndex(s, sep)+1:]
+}
@@ -0,0 +1,364 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func publish(t *testing.T, backend *fake.Backend, in *sns.PublishInput) {
+	t.Helper()
+	_, err := backend.SNS().Publish(context.Background(), in)
+	assert.NoError(t, err)
+}
+
+func TestBackend_Subscriber(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publish and receive", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders")
+		assert.NoError(t, err)
+		assert.Equal(t, topicArn, s.Config.TopicARN)
+
+		publish(t, backend, &sns.PublishInput{
+			TopicArn: aws.String(topicArn),
+			Subject:  aws.String("created"),
+			Message:  aws.String(`{"id": "1"}`),
+			MessageAttributes: map[string]snstypes.MessageAttributeValue{
+				"event": {DataType: aws.String("String"), StringValue: aws.String("created")},
+			},
+		})
+
+		msg, err := s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "Notification", msg.Type)
+			assert.Equal(t, topicArn, msg.TopicARN)
+			assert.Equal(t, "created", msg.Subject)
+			assert.Equal(t, `{"id": "1"}`, msg.Message)
+			assert.Equal(t, "created", msg.MessageAttributes["event"].String())
+			assert.WithinDuration(t, time.Now(), msg.Timestamp, time.Second)
+		}
+		assert.Empty(t, backend.Messages(s.Config.QueueURL), "message deleted on receipt")
+
+		assert.NoError(t, s.Cleanup(ctx))
+		assert.Empty(t, backend.QueueURLs())
+		subs, err := backend.SNS().ListSubscriptions(ctx, &sns.ListSubscriptionsInput{})
+		assert.NoError(t, err)
+		assert.Empty(t, subs.Subscriptions)
+	})
+
+	t.Run("raw message delivery", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithRawMessageDelivery())
+		assert.NoError(t, err)
+
+		publish(t, backend, &sns.PublishInput{
+			TopicArn: aws.String(topicArn),
+			Message:  aws.String("hello"),
+			MessageAttributes: map[string]snstypes.MessageAttributeValue{
+				"count": {DataType: aws.String("Number"), StringValue: aws.String("5")},
+			},
+		})
+
+		msg, err := s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "hello", msg.Body)
+			assert.Equal(t, snstesting.MessageAttribute{Type: "Number", Value: "5"}, msg.MessageAttributes["count"])
+		}
+	})
+
+	t.Run("filter policy", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		policy := snstesting.NewFilterPolicy().Equals("event", "created").Numeric("total", ">", 100)
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithFilterPolicy(policy.String()))
+		assert.NoError(t, err)
+
+		attrs := func(event, total string) map[string]snstypes.MessageAttributeValue {
+			return map[string]snstypes.MessageAttributeValue{
+				"event": {DataType: aws.String("String"), StringValue: aws.String(event)},
+				"total": {DataType: aws.String("Number"), StringValue: aws.String(total)},
+			}
+		}
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("small"), MessageAttributes: attrs("created", "10")})
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("paid"), MessageAttributes: attrs("paid", "200")})
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("big"), MessageAttributes: attrs("created", "200")})
+
+		msgs, err := s.ReceiveBatch(ctx, 10)
+		assert.NoError(t, err)
+		if assert.Len(t, msgs, 1) {
+			assert.Equal(t, "big", msgs[0].Message)
+		}
+	})
+
+	t.Run("fifo", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders.fifo")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders.fifo", snstesting.WithManualAck())
+		assert.NoError(t, err)
+
+		for _, body := range []string{"1", "2", "2"} {
+			publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String(body), MessageGroupId: aws.String("a")})
+		}
+		assert.Len(t, backend.Messages(s.Config.QueueURL), 2, "duplicate dropped")
+
+		first, err := s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, first) {
+			assert.Equal(t, "1", first.Message)
+			assert.Equal(t, "a", first.MessageGroupID)
+			assert.NotEmpty(t, first.SequenceNumber)
+		}
+
+		start := time.Now()
+		other := &snstesting.Subscriber{SQS: s.SQS, Config: snstesting.Config{QueueURL: s.Config.QueueURL, WaitTime: time.Second}}
+		blocked, err := other.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, blocked, "group is blocked until first message is acknowledged")
+		assert.GreaterOrEqual(t, time.Since(start), time.Second, "long polling")
+
+		assert.NoError(t, s.Ack(ctx, first))
+		second, err := s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, second) {
+			assert.Equal(t, "2", second.Message)
+		}
+	})
+
+	t.Run("multiple topics", func(t *testing.T) {
+		backend := fake.New()
+		orders := backend.CreateTopic("orders")
+		payments := backend.CreateTopic("payments")
+
+		s, err := snstesting.NewMultiSubscriber(ctx, backend.SNS(), backend.SQS(), []string{"orders", "payments"})
+		assert.NoError(t, err)
+
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(payments), Message: aws.String("paid")})
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(orders), Message: aws.String("created")})
+
+		msgs, err := s.ReceiveBatch(ctx, 2)
+		assert.NoError(t, err)
+		if assert.Len(t, msgs, 2) {
+			assert.Equal(t, payments, msgs[0].TopicARN)
+			assert.Equal(t, orders, msgs[1].TopicARN)
+		}
+	})
+
+	t.Run("nack and visibility timeout", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders",
+			snstesting.WithManualAck(), snstesting.WithVisibilityTimeout(time.Second), snstesting.WithWaitTime(2*time.Second))
+		assert.NoError(t, err)
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("hello")})
+
+		msg, err := s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, s.Nack(ctx, msg))
+
+		msg, err = s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, msg, "visible again right after nack")
+
+		start := time.Now()
+		msg, err = s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, msg, "visible again after visibility timeout")
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		_, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders")
+		assert.NoError(t, err)
+
+		swept, err := snstesting.Sweep(ctx, backend.SNS(), backend.SQS(), 0)
+		assert.NoError(t, err)
+		if assert.Len(t, swept, 1) {
+			assert.Len(t, swept[0].Subscriptions, 1)
+		}
+		assert.Empty(t, backend.QueueURLs())
+	})
+}
+
+func TestSNS_Publish(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, policy string) (*fake.Backend, string, string) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		q, err := backend.SQS().CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("orders-queue")})
+		assert.NoError(t, err)
+		_, err = backend.SQS().SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl:   q.QueueUrl,
+			Attributes: map[string]string{"Policy": policy},
+		})
+		assert.NoError(t, err)
+		_, err = backend.SNS().Subscribe(ctx, &sns.SubscribeInput{
+			TopicArn: aws.String(topicArn),
+			Protocol: aws.String("sqs"),
+			Endpoint: aws.String("arn:aws:sqs:us-east-1:123456789012:orders-queue"),
+		})
+		assert.NoError(t, err)
+		return backend, topicArn, *q.QueueUrl
+	}
+
+	t.Run("queue policy allows", func(t *testing.T) {
+		backend, topicArn, queueURL := setup(t, `{"Statement": {"Effect": "Allow", "Principal": "*", "Action": "sqs:*",
+			"Condition": {"ArnLike": {"aws:SourceArn": "arn:aws:sns:*:123456789012:*"}}}}`)
+
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("hello")})
+		assert.Len(t, backend.Messages(queueURL), 1)
+	})
+
+	t.Run("queue policy denies", func(t *testing.T) {
+		backend, topicArn, queueURL := setup(t, `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "SQS:SendMessage",
+			"Condition": {"ArnEquals": {"aws:SourceArn": "arn:aws:sns:us-east-1:123456789012:payments"}}}]}`)
+
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("hello")})
+		assert.Empty(t, backend.Messages(queueURL))
+	})
+
+	t.Run("no queue policy", func(t *testing.T) {
+		backend, topicArn, queueURL := setup(t, "")
+
+		publish(t, backend, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("hello")})
+		assert.Empty(t, backend.Messages(queueURL))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		_, err := backend.SNS().Publish(ctx, &sns.PublishInput{TopicArn: aws.String(topicArn + "x"), Message: aws.String("hello")})
+		var notFound *snstypes.NotFoundException
+		assert.True(t, errors.As(err, &notFound))
+
+		_, err = backend.SNS().Publish(ctx, &sns.PublishInput{TopicArn: aws.String(topicArn)})
+		assert.EqualError(t, err, "api error InvalidParameter: Invalid parameter: Empty message")
+
+		_, err = backend.SNS().Publish(ctx, &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String("hello"), MessageGroupId: aws.String("a")})
+		assert.EqualError(t, err, "api error InvalidParameter: Invalid parameter: MessageGroupId and MessageDeduplicationId are valid only for FIFO topics")
+
+		fifoArn := backend.CreateTopic("payments.fifo")
+		_, err = backend.SNS().Publish(ctx, &sns.PublishInput{TopicArn: aws.String(fifoArn), Message: aws.String("hello")})
+		assert.EqualError(t, err, "api error InvalidParameter: Invalid parameter: The MessageGroupId parameter is required for FIFO topics")
+	})
+}
+
+func TestSQS(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("queue does not exist", func(t *testing.T) {
+		_, err := fake.New().SQS().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String("http://nope")})
+		var notExist *sqstypes.QueueDoesNotExist
+		assert.True(t, errors.As(err, &notExist))
+	})
+
+	t.Run("long polling ends when queue is deleted", func(t *testing.T) {
+		backend := fake.New()
+		q, err := backend.SQS().CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("q")})
+		assert.NoError(t, err)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_, _ = backend.SQS().DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: q.QueueUrl})
+		}()
+		_, err = backend.SQS().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: q.QueueUrl, WaitTimeSeconds: 20})
+		var notExist *sqstypes.QueueDoesNotExist
+		assert.True(t, errors.As(err, &notExist))
+	})
+
+	t.Run("long polling wakes up on new message", func(t *testing.T) {
+		backend := fake.New()
+		q, err := backend.SQS().CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("q")})
+		assert.NoError(t, err)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_, _ = backend.SQS().SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: q.QueueUrl, MessageBody: aws.String("hello")})
+		}()
+		start := time.Now()
+		out, err := backend.SQS().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: q.QueueUrl, WaitTimeSeconds: 20})
+		assert.NoError(t, err)
+		assert.Len(t, out.Messages, 1)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("invalid receipt handle", func(t *testing.T) {
+		backend := fake.New()
+		q, err := backend.SQS().CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("q")})
+		assert.NoError(t, err)
+
+		_, err = backend.SQS().DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: q.QueueUrl, ReceiptHandle: aws.String("foo")})
+		var invalid *sqstypes.ReceiptHandleIsInvalid
+		assert.True(t, errors.As(err, &invalid))
+
+		out, err := backend.SQS().DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: q.QueueUrl,
+			Entries:  []sqstypes.DeleteMessageBatchRequestEntry{{Id: aws.String("1"), ReceiptHandle: aws.String("foo")}},
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, out.Failed, 1) {
+			assert.Equal(t, "ReceiptHandleIsInvalid", aws.ToString(out.Failed[0].Code))
+		}
+	})
+
+	t.Run("queue attributes", func(t *testing.T) {
+		backend := fake.New()
+		q, err := backend.SQS().CreateQueue(ctx, &sqs.CreateQueueInput{
+			QueueName:  aws.String("q"),
+			Attributes: map[string]string{"VisibilityTimeout": "60"},
+			Tags:       map[string]string{"team": "orders"},
+		})
+		assert.NoError(t, err)
+		_, err = backend.SQS().SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: q.QueueUrl, MessageBody: aws.String("hello")})
+		assert.NoError(t, err)
+
+		out, err := backend.SQS().GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl: q.QueueUrl,
+			AttributeNames: []sqstypes.QueueAttributeName{
+				sqstypes.QueueAttributeNameQueueArn,
+				sqstypes.QueueAttributeNameVisibilityTimeout,
+				sqstypes.QueueAttributeNameApproximateNumberOfMessages,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"QueueArn":                    "arn:aws:sqs:us-east-1:123456789012:q",
+			"VisibilityTimeout":           "60",
+			"ApproximateNumberOfMessages": "1",
+		}, out.Attributes)
+
+		tags, err := backend.SQS().ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: q.QueueUrl})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"team": "orders"}, tags.Tags)
+
+		_, err = backend.SQS().CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("q"), Attributes: map[string]string{"VisibilityTimeout": "30"}})
+		var exists *sqstypes.QueueNameExists
+		assert.True(t, errors.As(err, &exists))
+	})
+}
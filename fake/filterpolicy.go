@@ -0,0 +1,334 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// filterPolicy is parsed subscription filter policy, see
+// https://docs.aws.amazon.com/sns/latest/dg/sns-subscription-filter-policies.html
+type filterPolicy struct {
+	rules map[string]interface{}
+}
+
+func parseFilterPolicy(policy string) (*filterPolicy, error) {
+	dec := json.NewDecoder(bytes.NewBufferString(policy))
+	dec.UseNumber()
+	var rules map[string]interface{}
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("policy is not a JSON object: %v", err)
+	}
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+	return &filterPolicy{rules: rules}, nil
+}
+
+func validateRules(rules map[string]interface{}) error {
+	for key, value := range rules {
+		switch v := value.(type) {
+		case []interface{}:
+			if key == "$or" {
+				for _, alt := range v {
+					nested, ok := alt.(map[string]interface{})
+					if !ok {
+						return errors.New("$or has to be a list of objects")
+					}
+					if err := validateRules(nested); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			for _, cond := range v {
+				if err := validateCondition(key, cond); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}:
+			if err := validateRules(v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("match value of %s must be a list", key)
+		}
+	}
+	return nil
+}
+
+func validateCondition(key string, cond interface{}) error {
+	obj, ok := cond.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if len(obj) != 1 {
+		return fmt.Errorf("condition of %s must have exactly one operator", key)
+	}
+	for op, arg := range obj {
+		switch op {
+		case "prefix", "suffix", "equals-ignore-case":
+			if _, ok := arg.(string); !ok {
+				return fmt.Errorf("%s of %s must be a string", op, key)
+			}
+		case "exists":
+			if _, ok := arg.(bool); !ok {
+				return fmt.Errorf("exists of %s must be a boolean", key)
+			}
+		case "anything-but":
+		case "numeric":
+			if _, err := numericRange(arg); err != nil {
+				return fmt.Errorf("numeric of %s: %v", key, err)
+			}
+		default:
+			return fmt.Errorf("unrecognized match type %s", op)
+		}
+	}
+	return nil
+}
+
+// matches tells if publication passes the policy, in given scope.
+func (p *filterPolicy) matches(pub *publication, scope string) bool {
+	if scope == "MessageBody" {
+		dec := json.NewDecoder(bytes.NewBufferString(pub.message))
+		dec.UseNumber()
+		var body map[string]interface{}
+		if err := dec.Decode(&body); err != nil {
+			// non JSON bodies never match body policies
+			return false
+		}
+		return matchObject(p.rules, body)
+	}
+
+	attrs := map[string]interface{}{}
+	for name, attr := range pub.attrs {
+		dataType := aws.ToString(attr.DataType)
+		switch {
+		case strings.HasPrefix(dataType, "String.Array"):
+			var v []interface{}
+			dec := json.NewDecoder(bytes.NewBufferString(aws.ToString(attr.StringValue)))
+			dec.UseNumber()
+			if dec.Decode(&v) == nil {
+				attrs[name] = v
+			}
+		case strings.HasPrefix(dataType, "Number"):
+			attrs[name] = json.Number(aws.ToString(attr.StringValue))
+		case strings.HasPrefix(dataType, "String"):
+			attrs[name] = aws.ToString(attr.StringValue)
+		default:
+			// binary attributes are ignored by filtering, yet they exist
+			attrs[name] = binaryValue{}
+		}
+	}
+	return matchObject(p.rules, attrs)
+}
+
+type binaryValue struct{}
+
+// matchObject checks all policy keys, nested objects are matched recursively.
+func matchObject(rules, doc map[string]interface{}) bool {
+	for key, rule := range rules {
+		if key == "$or" {
+			alternatives := rule.([]interface{})
+			matched := false
+			for _, alt := range alternatives {
+				if matchObject(alt.(map[string]interface{}), doc) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+
+		value, present := doc[key]
+		switch r := rule.(type) {
+		case map[string]interface{}:
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				nested = map[string]interface{}{}
+			}
+			if !matchObject(r, nested) {
+				return false
+			}
+		case []interface{}:
+			if !matchConditions(r, value, present) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchConditions tells if value satisfies any of conditions, array values match when any element does.
+func matchConditions(conditions []interface{}, value interface{}, present bool) bool {
+	for _, cond := range conditions {
+		if obj, ok := cond.(map[string]interface{}); ok {
+			if exists, ok := obj["exists"]; ok {
+				if exists.(bool) == present {
+					return true
+				}
+				continue
+			}
+		}
+		if !present {
+			continue
+		}
+		if arr, ok := value.([]interface{}); ok {
+			for _, v := range arr {
+				if matchCondition(cond, v) {
+					return true
+				}
+			}
+			continue
+		}
+		if matchCondition(cond, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCondition(cond, value interface{}) bool {
+	if _, ok := value.(binaryValue); ok {
+		return false
+	}
+
+	switch c := cond.(type) {
+	case string:
+		s, ok := value.(string)
+		return ok && s == c
+	case json.Number:
+		return numbersEqual(c, value)
+	case nil:
+		return value == nil
+	case bool:
+		b, ok := value.(bool)
+		return ok && b == c
+	case map[string]interface{}:
+		for op, arg := range c {
+			switch op {
+			case "prefix":
+				s, ok := value.(string)
+				return ok && strings.HasPrefix(s, arg.(string))
+			case "suffix":
+				s, ok := value.(string)
+				return ok && strings.HasSuffix(s, arg.(string))
+			case "equals-ignore-case":
+				s, ok := value.(string)
+				return ok && strings.EqualFold(s, arg.(string))
+			case "anything-but":
+				return !anythingBut(arg, value)
+			case "numeric":
+				r, _ := numericRange(arg)
+				n, ok := toNumber(value)
+				return ok && r.contains(n)
+			}
+		}
+	}
+	return false
+}
+
+// anythingBut tells if value is one of excluded values.
+func anythingBut(arg, value interface{}) bool {
+	switch a := arg.(type) {
+	case []interface{}:
+		for _, v := range a {
+			if anythingBut(v, value) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		if prefix, ok := a["prefix"].(string); ok {
+			s, ok := value.(string)
+			return ok && strings.HasPrefix(s, prefix)
+		}
+		return false
+	case json.Number:
+		return numbersEqual(a, value)
+	case string:
+		s, ok := value.(string)
+		return ok && s == a
+	}
+	return false
+}
+
+func numbersEqual(n json.Number, value interface{}) bool {
+	want, err := n.Float64()
+	if err != nil {
+		return false
+	}
+	got, ok := toNumber(value)
+	return ok && want == got
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// numberRange is parsed 'numeric' condition, e.g. [">", 0, "<=", 5] or ["=", 10].
+type numberRange struct {
+	lower, upper         float64
+	lowerIncl, upperIncl bool
+}
+
+func numericRange(arg interface{}) (numberRange, error) {
+	r := numberRange{lower: math.Inf(-1), upper: math.Inf(1), lowerIncl: true, upperIncl: true}
+	args, ok := arg.([]interface{})
+	if !ok || len(args) == 0 || len(args)%2 != 0 || len(args) > 4 {
+		return r, errors.New("expected list of operator and value pairs")
+	}
+	for i := 0; i < len(args); i += 2 {
+		op, ok := args[i].(string)
+		if !ok {
+			return r, errors.New("operator has to be a string")
+		}
+		num, ok := args[i+1].(json.Number)
+		if !ok {
+			return r, errors.New("value has to be a number")
+		}
+		v, err := strconv.ParseFloat(num.String(), 64)
+		if err != nil {
+			return r, err
+		}
+		switch op {
+		case "=":
+			r.lower, r.upper = v, v
+		case ">":
+			r.lower, r.lowerIncl = v, false
+		case ">=":
+			r.lower, r.lowerIncl = v, true
+		case "<":
+			r.upper, r.upperIncl = v, false
+		case "<=":
+			r.upper, r.upperIncl = v, true
+		default:
+			return r, fmt.Errorf("unrecognized numeric range operator: %s", op)
+		}
+	}
+	return r, nil
+}
+
+func (r numberRange) contains(v float64) bool {
+	if v < r.lower || (v == r.lower && !r.lowerIncl) {
+		return false
+	}
+	if v > r.upper || (v == r.upper && !r.upperIncl) {
+		return false
+	}
+	return true
+}
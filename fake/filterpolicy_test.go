@@ -0,0 +1,103 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	// delivered tells if message passes the policy, by publishing it on a topic watched with the policy
+	delivered := func(t *testing.T, policy, scope string, in *sns.PublishInput) bool {
+		t.Helper()
+
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+		opts := []snstesting.Option{snstesting.WithFilterPolicy(policy)}
+		if scope != "" {
+			opts = append(opts, snstesting.WithFilterPolicyScope(scope))
+		}
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", opts...)
+		if !assert.NoError(t, err) {
+			return false
+		}
+
+		in.TopicArn = aws.String(topicArn)
+		_, err = backend.SNS().Publish(ctx, in)
+		assert.NoError(t, err)
+		return len(backend.Messages(s.Config.QueueURL)) == 1
+	}
+	str := func(v string) snstypes.MessageAttributeValue {
+		return snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+	}
+	num := func(v string) snstypes.MessageAttributeValue {
+		return snstypes.MessageAttributeValue{DataType: aws.String("Number"), StringValue: aws.String(v)}
+	}
+	arr := func(v string) snstypes.MessageAttributeValue {
+		return snstypes.MessageAttributeValue{DataType: aws.String("String.Array"), StringValue: aws.String(v)}
+	}
+	attrs := func(kv ...interface{}) *sns.PublishInput {
+		in := &sns.PublishInput{Message: aws.String("hello"), MessageAttributes: map[string]snstypes.MessageAttributeValue{}}
+		for i := 0; i < len(kv); i += 2 {
+			in.MessageAttributes[kv[i].(string)] = kv[i+1].(snstypes.MessageAttributeValue)
+		}
+		return in
+	}
+	body := func(message string) *sns.PublishInput {
+		return &sns.PublishInput{Message: aws.String(message)}
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		scope  string
+		in     *sns.PublishInput
+		want   bool
+	}{
+		{name: "exact", policy: `{"event": ["created", "paid"]}`, in: attrs("event", str("paid")), want: true},
+		{name: "exact mismatch", policy: `{"event": ["created"]}`, in: attrs("event", str("paid"))},
+		{name: "missing attribute", policy: `{"event": ["created"]}`, in: attrs()},
+		{name: "all keys have to match", policy: `{"event": ["created"], "store": ["a"]}`, in: attrs("event", str("created"), "store", str("b"))},
+		{name: "number", policy: `{"total": [10]}`, in: attrs("total", num("10.0")), want: true},
+		{name: "number is not a string", policy: `{"total": ["10"]}`, in: attrs("total", num("10"))},
+		{name: "string array", policy: `{"tags": ["b"]}`, in: attrs("tags", arr(`["a", "b"]`)), want: true},
+		{name: "prefix", policy: `{"event": [{"prefix": "order_"}]}`, in: attrs("event", str("order_created")), want: true},
+		{name: "suffix", policy: `{"event": [{"suffix": "_paid"}]}`, in: attrs("event", str("order_created"))},
+		{name: "equals ignore case", policy: `{"event": [{"equals-ignore-case": "CREATED"}]}`, in: attrs("event", str("created")), want: true},
+		{name: "anything but", policy: `{"event": [{"anything-but": ["paid", "cancelled"]}]}`, in: attrs("event", str("created")), want: true},
+		{name: "anything but excluded", policy: `{"event": [{"anything-but": "paid"}]}`, in: attrs("event", str("paid"))},
+		{name: "anything but prefix", policy: `{"event": [{"anything-but": {"prefix": "test_"}}]}`, in: attrs("event", str("test_created"))},
+		{name: "numeric range", policy: `{"total": [{"numeric": [">", 0, "<=", 100]}]}`, in: attrs("total", num("100")), want: true},
+		{name: "numeric range exceeded", policy: `{"total": [{"numeric": [">", 0, "<", 100]}]}`, in: attrs("total", num("100"))},
+		{name: "exists", policy: `{"event": [{"exists": true}]}`, in: attrs("event", str("x")), want: true},
+		{name: "not exists", policy: `{"event": [{"exists": false}]}`, in: attrs(), want: true},
+		{name: "or", policy: `{"$or": [{"event": ["created"]}, {"total": [{"numeric": [">", 100]}]}]}`, in: attrs("total", num("200")), want: true},
+		{name: "body", policy: `{"order": {"status": ["paid"]}}`, scope: "MessageBody", in: body(`{"order": {"status": "paid"}}`), want: true},
+		{name: "body array", policy: `{"items": {"sku": ["b"]}}`, scope: "MessageBody", in: body(`{"items": {"sku": ["a", "b"]}}`), want: true},
+		{name: "body mismatch", policy: `{"order": {"status": ["paid"]}}`, scope: "MessageBody", in: body(`{"order": {"status": "created"}}`)},
+		{name: "body not a json", policy: `{"order": ["paid"]}`, scope: "MessageBody", in: body(`paid`)},
+		{name: "body numeric", policy: `{"total": [{"numeric": [">=", 10]}]}`, scope: "MessageBody", in: body(`{"total": 10}`), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, delivered(t, tt.policy, tt.scope, tt.in))
+		})
+	}
+
+	t.Run("invalid policy", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		_, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", snstesting.WithFilterPolicy(`{"event": "created"}`))
+		assert.EqualError(t, err, "subscribe failure: api error InvalidParameter: Invalid parameter: FilterPolicy: match value of event must be a list")
+		assert.Empty(t, backend.QueueURLs(), "queue cleaned up")
+	})
+}
@@ -0,0 +1,153 @@
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+)
+
+// queuePolicy is the part of IAM policy language SNS delivery depends on.
+type queuePolicy struct {
+	Statement statements
+}
+
+// statements may be given as a single statement or a list of them.
+type statements []statement
+
+func (s *statements) UnmarshalJSON(b []byte) error {
+	var one statement
+	if err := json.Unmarshal(b, &one); err == nil {
+		*s = statements{one}
+		return nil
+	}
+	var many []statement
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*s = many
+	return nil
+}
+
+type statement struct {
+	Effect    string
+	Principal interface{}
+	Action    stringOrList
+	Resource  stringOrList
+	Condition map[string]map[string]stringOrList
+}
+
+// stringOrList is policy value that may be given as a string or a list of strings.
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*s = stringOrList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("expected string or list of strings")
+	}
+	*s = many
+	return nil
+}
+
+func parseQueuePolicy(policy string) (*queuePolicy, error) {
+	var p queuePolicy
+	if err := json.Unmarshal([]byte(policy), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// allowsSendFrom tells if queue policy lets the topic send messages, explicit deny wins over allow.
+// Only aws:SourceArn and aws:SourceAccount conditions are understood, statements with other conditions are ignored.
+func (q *queue) allowsSendFrom(topicArn string, accountID string) bool {
+	policy, ok := q.attrs["Policy"]
+	if !ok || policy == "" {
+		return false
+	}
+	p, err := parseQueuePolicy(policy)
+	if err != nil {
+		return false
+	}
+
+	allowed := false
+	for _, st := range p.Statement {
+		if !st.applies(q.arn, topicArn, accountID) {
+			continue
+		}
+		switch st.Effect {
+		case "Deny":
+			return false
+		case "Allow":
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+func (st statement) applies(queueArn, topicArn, accountID string) bool {
+	if !matchesAny(st.Action, "sqs:SendMessage", true) {
+		return false
+	}
+	if len(st.Resource) > 0 && !matchesAny(st.Resource, queueArn, false) {
+		return false
+	}
+	for operator, conditions := range st.Condition {
+		for key, values := range conditions {
+			var actual string
+			switch strings.ToLower(key) {
+			case "aws:sourcearn":
+				actual = topicArn
+			case "aws:sourceaccount":
+				actual = accountID
+			default:
+				return false
+			}
+			if !conditionHolds(operator, values, actual) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func conditionHolds(operator string, values []string, actual string) bool {
+	switch operator {
+	case "ArnEquals", "StringEquals":
+		for _, v := range values {
+			if v == actual {
+				return true
+			}
+		}
+		return false
+	case "ArnLike", "StringLike":
+		return matchesAny(values, actual, false)
+	case "ArnNotEquals", "StringNotEquals":
+		return !conditionHolds("ArnEquals", values, actual)
+	case "ArnNotLike", "StringNotLike":
+		return !matchesAny(values, actual, false)
+	default:
+		return false
+	}
+}
+
+// matchesAny matches value against policy patterns with '*' and '?' wildcards.
+func matchesAny(patterns []string, value string, ignoreCase bool) bool {
+	if ignoreCase {
+		value = strings.ToLower(value)
+	}
+	for _, p := range patterns {
+		if ignoreCase {
+			p = strings.ToLower(p)
+		}
+		// path.Match treats '/' specially, ARNs and actions never contain it in places that matter here
+		if ok, err := path.Match(p, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,610 @@
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prozz/snstesting"
+)
+
+var _ snstesting.SNSAPI = (*SNS)(nil)
+
+// listTopicsPageSize is the same as in real SNS.
+const listTopicsPageSize = 100
+
+// SNS is fake SNS client, see Backend.
+type SNS struct {
+	b *Backend
+}
+
+type topic struct {
+	name  string
+	arn   string
+	fifo  bool
+	attrs map[string]string
+	seq   int64
+	// dedup keeps deduplication IDs of FIFO topic, with time they were seen at
+	dedup map[string]time.Time
+}
+
+type subscription struct {
+	arn      string
+	topicArn string
+	endpoint string
+	attrs    map[string]string
+	policy   *filterPolicy
+}
+
+var topicName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,256}$`)
+
+// CreateTopic creates topic, FIFO topics have to be named with '.fifo' suffix and have FifoTopic attribute set.
+func (s *SNS) CreateTopic(_ context.Context, in *sns.CreateTopicInput, _ ...func(*sns.Options)) (*sns.CreateTopicOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	t, err := s.b.createTopic(aws.ToString(in.Name), in.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return &sns.CreateTopicOutput{TopicArn: aws.String(t.arn)}, nil
+}
+
+func (b *Backend) createTopic(name string, attrs map[string]string) (*topic, error) {
+	fifo := strings.HasSuffix(name, ".fifo")
+	if !topicName.MatchString(strings.TrimSuffix(name, ".fifo")) {
+		return nil, apiError("InvalidParameter", "Invalid parameter: Topic Name")
+	}
+	if fifo != (attrs["FifoTopic"] == "true") {
+		return nil, apiError("InvalidParameter", "Invalid parameter: Fifo Topic names must end with .fifo and must be made up of only uppercase and lowercase ASCII letters, numbers, underscores, and hyphens, and must be between 1 and 256 characters long.")
+	}
+
+	arn := b.arn("sns", name)
+	if t, ok := b.topics[arn]; ok {
+		return t, nil
+	}
+
+	t := &topic{name: name, arn: arn, fifo: fifo, attrs: copyMap(attrs), dedup: map[string]time.Time{}}
+	if t.attrs == nil {
+		t.attrs = map[string]string{}
+	}
+	if fifo {
+		t.attrs["FifoTopic"] = "true"
+	}
+	b.topics[arn] = t
+	b.topicOrder = append(b.topicOrder, arn)
+	return t, nil
+}
+
+// DeleteTopic removes topic together with its subscriptions.
+func (s *SNS) DeleteTopic(_ context.Context, in *sns.DeleteTopicInput, _ ...func(*sns.Options)) (*sns.DeleteTopicOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	arn := aws.ToString(in.TopicArn)
+	delete(s.b.topics, arn)
+	s.b.topicOrder = remove(s.b.topicOrder, arn)
+	for _, subArn := range s.b.subOrder {
+		if s.b.subscriptions[subArn].topicArn == arn {
+			s.b.deleteSubscription(subArn)
+		}
+	}
+	return &sns.DeleteTopicOutput{}, nil
+}
+
+// GetTopicAttributes gives attributes the topic was created with, together with ARN, owner, subscription counts
+// and default access policy, unless it was created with one.
+func (s *SNS) GetTopicAttributes(_ context.Context, in *sns.GetTopicAttributesInput, _ ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	t, ok := s.b.topics[aws.ToString(in.TopicArn)]
+	if !ok {
+		return nil, &snstypes.NotFoundException{Message: aws.String("Topic does not exist")}
+	}
+	confirmed := 0
+	for _, sub := range s.b.subscriptions {
+		if sub.topicArn == t.arn {
+			confirmed++
+		}
+	}
+	attrs := map[string]string{
+		"TopicArn":                t.arn,
+		"Owner":                   s.b.AccountID,
+		"DisplayName":             "",
+		"Policy":                  fmt.Sprintf(defaultTopicPolicy, t.arn, s.b.AccountID),
+		"SubscriptionsConfirmed":  strconv.Itoa(confirmed),
+		"SubscriptionsPending":    "0",
+		"SubscriptionsDeleted":    "0",
+		"EffectiveDeliveryPolicy": `{"http":{"defaultHealthyRetryPolicy":{"minDelayTarget":20,"maxDelayTarget":20,"numRetries":3,"numMaxDelayRetries":0,"numNoDelayRetries":0,"numMinDelayRetries":0,"backoffFunction":"linear"},"disableSubscriptionOverrides":false}}`,
+	}
+	for name, value := range t.attrs {
+		attrs[name] = value
+	}
+	return &sns.GetTopicAttributesOutput{Attributes: attrs}, nil
+}
+
+// defaultTopicPolicy is access policy of topics created without one, allowing the owner account everything.
+const defaultTopicPolicy = `{"Version":"2008-10-17","Id":"__default_policy_ID","Statement":[{"Sid":"__default_statement_ID","Effect":"Allow","Principal":{"AWS":"*"},"Action":["SNS:GetTopicAttributes","SNS:SetTopicAttributes","SNS:AddPermission","SNS:RemovePermission","SNS:DeleteTopic","SNS:Subscribe","SNS:ListSubscriptionsByTopic","SNS:Publish"],"Resource":%q,"Condition":{"StringEquals":{"AWS:SourceOwner":%q}}}]}`
+
+// ListTopics lists topics in order of creation, paginated.
+func (s *SNS) ListTopics(_ context.Context, in *sns.ListTopicsInput, _ ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	start, end, next, err := page(len(s.b.topicOrder), in.NextToken, listTopicsPageSize)
+	if err != nil {
+		return nil, err
+	}
+	out := &sns.ListTopicsOutput{NextToken: next}
+	for _, arn := range s.b.topicOrder[start:end] {
+		out.Topics = append(out.Topics, snstypes.Topic{TopicArn: aws.String(arn)})
+	}
+	return out, nil
+}
+
+// Subscribe subscribes SQS queue to the topic, other protocols are not supported.
+// Subscribing the same queue again with the same attributes gives existing subscription.
+func (s *SNS) Subscribe(_ context.Context, in *sns.SubscribeInput, _ ...func(*sns.Options)) (*sns.SubscribeOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	t, ok := s.b.topics[aws.ToString(in.TopicArn)]
+	if !ok {
+		return nil, &snstypes.NotFoundException{Message: aws.String("Topic does not exist")}
+	}
+	if aws.ToString(in.Protocol) != "sqs" {
+		return nil, apiError("InvalidParameter", "Invalid parameter: Protocol, only sqs is supported by fake")
+	}
+	endpoint := aws.ToString(in.Endpoint)
+	if !strings.HasPrefix(endpoint, "arn:aws:sqs:") {
+		return nil, apiError("InvalidParameter", "Invalid parameter: SQS endpoint ARN")
+	}
+	if t.fifo && !strings.HasSuffix(endpoint, ".fifo") {
+		return nil, apiError("InvalidParameter", "Invalid parameter: Invalid parameter: Endpoint Reason: FIFO SNS Topics currently only support FIFO SQS queues as endpoints")
+	}
+
+	sub := &subscription{
+		topicArn: t.arn,
+		endpoint: endpoint,
+		attrs:    map[string]string{},
+	}
+	for name, value := range in.Attributes {
+		if err := sub.setAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, arn := range s.b.subOrder {
+		existing := s.b.subscriptions[arn]
+		if existing.topicArn == sub.topicArn && existing.endpoint == sub.endpoint {
+			if !equalMaps(existing.attrs, sub.attrs) {
+				return nil, apiError("InvalidParameter", "Invalid parameter: Attributes Reason: Subscription already exists with different attributes")
+			}
+			return &sns.SubscribeOutput{SubscriptionArn: aws.String(arn)}, nil
+		}
+	}
+
+	sub.arn = t.arn + ":" + s.b.nextID()
+	s.b.subscriptions[sub.arn] = sub
+	s.b.subOrder = append(s.b.subOrder, sub.arn)
+	return &sns.SubscribeOutput{SubscriptionArn: aws.String(sub.arn)}, nil
+}
+
+func (sub *subscription) setAttribute(name, value string) error {
+	switch name {
+	case "RawMessageDelivery":
+		if value != "true" && value != "false" {
+			return apiError("InvalidParameter", "Invalid parameter: Attributes Reason: RawMessageDelivery: Invalid value [%s]. Must be true or false.", value)
+		}
+	case "FilterPolicy":
+		if value == "" {
+			sub.policy = nil
+			break
+		}
+		p, err := parseFilterPolicy(value)
+		if err != nil {
+			return apiError("InvalidParameter", "Invalid parameter: FilterPolicy: %v", err)
+		}
+		sub.policy = p
+	case "FilterPolicyScope":
+		if value != "MessageAttributes" && value != "MessageBody" {
+			return apiError("InvalidParameter", "Invalid parameter: Attributes Reason: FilterPolicyScope: Invalid value [%s]. Please use either MessageBody or MessageAttributes", value)
+		}
+	case "DeliveryPolicy", "RedrivePolicy", "SubscriptionRoleArn":
+	default:
+		return apiError("InvalidParameter", "Invalid parameter: AttributeName")
+	}
+	sub.attrs[name] = value
+	return nil
+}
+
+// Unsubscribe removes subscription.
+func (s *SNS) Unsubscribe(_ context.Context, in *sns.UnsubscribeInput, _ ...func(*sns.Options)) (*sns.UnsubscribeOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	arn := aws.ToString(in.SubscriptionArn)
+	if _, ok := s.b.subscriptions[arn]; !ok {
+		return nil, &snstypes.NotFoundException{Message: aws.String("Subscription does not exist")}
+	}
+	s.b.deleteSubscription(arn)
+	return &sns.UnsubscribeOutput{}, nil
+}
+
+func (b *Backend) deleteSubscription(arn string) {
+	delete(b.subscriptions, arn)
+	b.subOrder = remove(b.subOrder, arn)
+}
+
+// GetSubscriptionAttributes gives attributes of the subscription, fake subscriptions are always confirmed.
+func (s *SNS) GetSubscriptionAttributes(_ context.Context, in *sns.GetSubscriptionAttributesInput, _ ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	sub, ok := s.b.subscriptions[aws.ToString(in.SubscriptionArn)]
+	if !ok {
+		return nil, &snstypes.NotFoundException{Message: aws.String("Subscription does not exist")}
+	}
+	attrs := map[string]string{
+		"SubscriptionArn":              sub.arn,
+		"TopicArn":                     sub.topicArn,
+		"Owner":                        s.b.AccountID,
+		"Protocol":                     "sqs",
+		"Endpoint":                     sub.endpoint,
+		"PendingConfirmation":          "false",
+		"ConfirmationWasAuthenticated": "true",
+		"RawMessageDelivery":           "false",
+	}
+	for name, value := range sub.attrs {
+		attrs[name] = value
+	}
+	return &sns.GetSubscriptionAttributesOutput{Attributes: attrs}, nil
+}
+
+// ListSubscriptions lists subscriptions in order of creation, paginated.
+func (s *SNS) ListSubscriptions(_ context.Context, in *sns.ListSubscriptionsInput, _ ...func(*sns.Options)) (*sns.ListSubscriptionsOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	start, end, next, err := page(len(s.b.subOrder), in.NextToken, listTopicsPageSize)
+	if err != nil {
+		return nil, err
+	}
+	out := &sns.ListSubscriptionsOutput{NextToken: next}
+	for _, arn := range s.b.subOrder[start:end] {
+		sub := s.b.subscriptions[arn]
+		out.Subscriptions = append(out.Subscriptions, snstypes.Subscription{
+			Endpoint:        aws.String(sub.endpoint),
+			Owner:           aws.String(s.b.AccountID),
+			Protocol:        aws.String("sqs"),
+			SubscriptionArn: aws.String(sub.arn),
+			TopicArn:        aws.String(sub.topicArn),
+		})
+	}
+	return out, nil
+}
+
+// Publish publishes message on the topic and delivers it to all subscribed queues, right away.
+// Delivery is skipped, silently as in real SNS, when subscription filter policy doesn't match the message
+// or queue policy doesn't allow the topic to send messages.
+func (s *SNS) Publish(_ context.Context, in *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	topicArn := aws.ToString(in.TopicArn)
+	if topicArn == "" {
+		topicArn = aws.ToString(in.TargetArn)
+	}
+	t, ok := s.b.topics[topicArn]
+	if !ok {
+		return nil, &snstypes.NotFoundException{Message: aws.String("Topic does not exist")}
+	}
+	return s.b.publish(t, in)
+}
+
+// PublishBatch publishes up to 10 messages on the topic, failures are reported per entry.
+func (s *SNS) PublishBatch(_ context.Context, in *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	t, ok := s.b.topics[aws.ToString(in.TopicArn)]
+	if !ok {
+		return nil, &snstypes.NotFoundException{Message: aws.String("Topic does not exist")}
+	}
+	entries := in.PublishBatchRequestEntries
+	switch {
+	case len(entries) == 0:
+		return nil, &snstypes.EmptyBatchRequestException{Message: aws.String("The batch request doesn't contain any entries")}
+	case len(entries) > 10:
+		return nil, &snstypes.TooManyEntriesInBatchRequestException{Message: aws.String("The batch request contains more entries than permissible")}
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		id := aws.ToString(e.Id)
+		if seen[id] {
+			return nil, &snstypes.BatchEntryIdsNotDistinctException{Message: aws.String("Two or more batch entries in the request have the same Id")}
+		}
+		seen[id] = true
+	}
+
+	out := &sns.PublishBatchOutput{}
+	for _, e := range entries {
+		published, err := s.b.publish(t, &sns.PublishInput{
+			Message:                e.Message,
+			MessageAttributes:      e.MessageAttributes,
+			MessageDeduplicationId: e.MessageDeduplicationId,
+			MessageGroupId:         e.MessageGroupId,
+			MessageStructure:       e.MessageStructure,
+			Subject:                e.Subject,
+		})
+		if err != nil {
+			code, msg := errorCodeAndMessage(err)
+			out.Failed = append(out.Failed, snstypes.BatchResultErrorEntry{Id: e.Id, Code: aws.String(code), Message: aws.String(msg), SenderFault: true})
+			continue
+		}
+		out.Successful = append(out.Successful, snstypes.PublishBatchResultEntry{
+			Id:             e.Id,
+			MessageId:      published.MessageId,
+			SequenceNumber: published.SequenceNumber,
+		})
+	}
+	return out, nil
+}
+
+func (b *Backend) publish(t *topic, in *sns.PublishInput) (*sns.PublishOutput, error) {
+	if in.Message == nil || *in.Message == "" {
+		return nil, apiError("InvalidParameter", "Invalid parameter: Empty message")
+	}
+	if aws.ToString(in.MessageStructure) != "" {
+		return nil, apiError("InvalidParameter", "Invalid parameter: MessageStructure is not supported by fake")
+	}
+	for name, attr := range in.MessageAttributes {
+		if err := validateAttribute(name, attr); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &publication{
+		id:        b.nextID(),
+		topic:     t,
+		subject:   aws.ToString(in.Subject),
+		message:   aws.ToString(in.Message),
+		attrs:     in.MessageAttributes,
+		timestamp: time.Now().UTC(),
+	}
+	if t.fifo {
+		if err := p.setFIFO(in); err != nil {
+			return nil, err
+		}
+		if seen, ok := t.dedup[p.dedupID]; ok && time.Since(seen) < dedupInterval {
+			// duplicate within deduplication interval is accepted, but not delivered
+			return &sns.PublishOutput{MessageId: aws.String(p.id), SequenceNumber: aws.String(p.seq)}, nil
+		}
+		t.dedup[p.dedupID] = time.Now()
+		t.seq++
+		p.seq = fmt.Sprintf("%020d", t.seq)
+	} else if in.MessageGroupId != nil || in.MessageDeduplicationId != nil {
+		return nil, apiError("InvalidParameter", "Invalid parameter: MessageGroupId and MessageDeduplicationId are valid only for FIFO topics")
+	}
+
+	for _, arn := range b.subOrder {
+		sub := b.subscriptions[arn]
+		if sub.topicArn != t.arn {
+			continue
+		}
+		b.deliver(sub, p)
+	}
+
+	out := &sns.PublishOutput{MessageId: aws.String(p.id)}
+	if t.fifo {
+		out.SequenceNumber = aws.String(p.seq)
+	}
+	return out, nil
+}
+
+// dedupInterval is deduplication interval of FIFO topics and queues.
+const dedupInterval = 5 * time.Minute
+
+// publication is message published on the topic.
+type publication struct {
+	id        string
+	topic     *topic
+	subject   string
+	message   string
+	attrs     map[string]snstypes.MessageAttributeValue
+	timestamp time.Time
+	groupID   string
+	dedupID   string
+	seq       string
+}
+
+func (p *publication) setFIFO(in *sns.PublishInput) error {
+	p.groupID = aws.ToString(in.MessageGroupId)
+	if p.groupID == "" {
+		return apiError("InvalidParameter", "Invalid parameter: The MessageGroupId parameter is required for FIFO topics")
+	}
+	p.dedupID = aws.ToString(in.MessageDeduplicationId)
+	if p.dedupID == "" {
+		if p.topic.attrs["ContentBasedDeduplication"] != "true" {
+			return apiError("InvalidParameter", "Invalid parameter: The topic should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+		}
+		sum := sha256.Sum256([]byte(p.message))
+		p.dedupID = hex.EncodeToString(sum[:])
+	}
+	return nil
+}
+
+func validateAttribute(name string, attr snstypes.MessageAttributeValue) error {
+	dataType := aws.ToString(attr.DataType)
+	switch {
+	case strings.HasPrefix(dataType, "String.Array"):
+		var v []interface{}
+		if err := json.Unmarshal([]byte(aws.ToString(attr.StringValue)), &v); err != nil {
+			return apiError("ParameterValueInvalid", "Could not cast message attribute '%s' value to String.Array", name)
+		}
+	case strings.HasPrefix(dataType, "String"):
+	case strings.HasPrefix(dataType, "Number"):
+		if _, err := strconv.ParseFloat(aws.ToString(attr.StringValue), 64); err != nil {
+			return apiError("ParameterValueInvalid", "Could not cast message attribute '%s' value to number", name)
+		}
+	case strings.HasPrefix(dataType, "Binary"):
+	default:
+		return apiError("ParameterValueInvalid", "The message attribute '%s' has an invalid message attribute type, the set of supported type prefixes is Binary, Number, and String", name)
+	}
+	return nil
+}
+
+// deliver puts publication into subscribed queue, if filter and queue policies allow it.
+func (b *Backend) deliver(sub *subscription, p *publication) {
+	q := b.queueByArn(sub.endpoint)
+	if q == nil || !q.allowsSendFrom(p.topic.arn, b.AccountID) {
+		return
+	}
+	if sub.policy != nil && !sub.policy.matches(p, sub.attrs["FilterPolicyScope"]) {
+		return
+	}
+
+	m := &message{
+		id:   b.nextID(),
+		sent: time.Now(),
+	}
+	if sub.attrs["RawMessageDelivery"] == "true" {
+		m.body = p.message
+		m.attrs = rawAttributes(p.attrs)
+	} else {
+		m.body = b.envelope(sub, p)
+	}
+	if p.topic.fifo {
+		m.groupID = p.groupID
+		m.dedupID = p.dedupID
+	}
+	q.enqueue(m)
+}
+
+// envelope is JSON of SNS notification, as delivered to SQS without raw message delivery.
+func (b *Backend) envelope(sub *subscription, p *publication) string {
+	type attribute struct {
+		Type  string
+		Value string
+	}
+	env := struct {
+		Type              string
+		MessageID         string               `json:"MessageId"`
+		SequenceNumber    string               `json:",omitempty"`
+		TopicARN          string               `json:"TopicArn"`
+		Subject           string               `json:",omitempty"`
+		Message           string               `json:"Message"`
+		Timestamp         string               `json:"Timestamp"`
+		SignatureVersion  string               `json:",omitempty"`
+		Signature         string               `json:",omitempty"`
+		SigningCertURL    string               `json:",omitempty"`
+		UnsubscribeURL    string               `json:"UnsubscribeURL"`
+		MessageAttributes map[string]attribute `json:",omitempty"`
+	}{
+		Type:           "Notification",
+		MessageID:      p.id,
+		SequenceNumber: p.seq,
+		TopicARN:       p.topic.arn,
+		Subject:        p.subject,
+		Message:        p.message,
+		Timestamp:      p.timestamp.Format("2006-01-02T15:04:05.000Z"),
+		// signature is never valid, it's there for completeness only
+		SignatureVersion: "1",
+		Signature:        "ZmFrZQ==",
+		SigningCertURL:   fmt.Sprintf("https://sns.%s.amazonaws.com/SimpleNotificationService-fake.pem", b.Region),
+		UnsubscribeURL:   fmt.Sprintf("https://sns.%s.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=%s", b.Region, sub.arn),
+	}
+	if len(p.attrs) > 0 {
+		env.MessageAttributes = map[string]attribute{}
+		for name, attr := range p.attrs {
+			value := aws.ToString(attr.StringValue)
+			if attr.BinaryValue != nil {
+				value = base64.StdEncoding.EncodeToString(attr.BinaryValue)
+			}
+			env.MessageAttributes[name] = attribute{Type: aws.ToString(attr.DataType), Value: value}
+		}
+	}
+
+	body, err := json.Marshal(env)
+	if err != nil {
+		// only strings inside, can't happen
+		panic(err)
+	}
+	return string(body)
+}
+
+func rawAttributes(attrs map[string]snstypes.MessageAttributeValue) map[string]sqstypes.MessageAttributeValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	v := make(map[string]sqstypes.MessageAttributeValue, len(attrs))
+	for name, attr := range attrs {
+		v[name] = sqstypes.MessageAttributeValue{
+			DataType:    attr.DataType,
+			StringValue: attr.StringValue,
+			BinaryValue: attr.BinaryValue,
+		}
+	}
+	return v
+}
+
+// page gives bounds of the page and token of the next one, tokens are just offsets.
+func page(n int, token *string, size int) (int, int, *string, error) {
+	start := 0
+	if token != nil {
+		var err error
+		start, err = strconv.Atoi(*token)
+		if err != nil || start < 0 || start > n {
+			return 0, 0, nil, apiError("InvalidParameter", "Invalid parameter: NextToken")
+		}
+	}
+	end := start + size
+	if end >= n {
+		return start, n, nil, nil
+	}
+	return start, end, aws.String(strconv.Itoa(end)), nil
+}
+
+func remove(s []string, v string) []string {
+	for i := range s {
+		if s[i] == v {
+			return append(s[:i:i], s[i+1:]...)
+		}
+	}
+	return s
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	v := make(map[string]string, len(m))
+	for k, val := range m {
+		v[k] = val
+	}
+	return v
+}
+
+func equalMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,739 @@
+package fake
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // SQS checksums are MD5
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prozz/snstesting"
+)
+
+var _ snstesting.SQSAPI = (*SQS)(nil)
+
+// SQS is fake SQS client, see Backend.
+type SQS struct {
+	b *Backend
+}
+
+type queue struct {
+	name    string
+	url     string
+	arn     string
+	fifo    bool
+	attrs   map[string]string
+	tags    map[string]string
+	created time.Time
+	msgs    []*message
+	// notify is closed and replaced whenever queue changes, waking up long polling receivers
+	notify chan struct{}
+	dedup  map[string]time.Time
+	seq    int64
+}
+
+type message struct {
+	id           string
+	body         string
+	attrs        map[string]sqstypes.MessageAttributeValue
+	sent         time.Time
+	delay        time.Duration
+	firstReceive time.Time
+	receiveCount int
+	visibleAt    time.Time
+	receipt      string
+	groupID      string
+	dedupID      string
+	seq          string
+}
+
+// queueDefaults are attributes every queue has, unless set otherwise.
+var queueDefaults = map[string]string{
+	"DelaySeconds":                  "0",
+	"MaximumMessageSize":            "262144",
+	"MessageRetentionPeriod":        "345600",
+	"ReceiveMessageWaitTimeSeconds": "0",
+	"VisibilityTimeout":             "30",
+}
+
+// settableAttributes may be given on queue creation or changed later.
+var settableAttributes = map[string]bool{
+	"DelaySeconds":                  true,
+	"MaximumMessageSize":            true,
+	"MessageRetentionPeriod":        true,
+	"Policy":                        true,
+	"ReceiveMessageWaitTimeSeconds": true,
+	"VisibilityTimeout":             true,
+	"RedrivePolicy":                 true,
+	"RedriveAllowPolicy":            true,
+	"KmsMasterKeyId":                true,
+	"KmsDataKeyReusePeriodSeconds":  true,
+	"SqsManagedSseEnabled":          true,
+	"FifoQueue":                     true,
+	"ContentBasedDeduplication":     true,
+	"DeduplicationScope":            true,
+	"FifoThroughputLimit":           true,
+}
+
+var queueName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,80}$`)
+
+// CreateQueue creates queue, FIFO queues have to be named with '.fifo' suffix and have FifoQueue attribute set.
+// Creating existing queue again gives its URL, as long as attributes are the same.
+func (s *SQS) CreateQueue(_ context.Context, in *sqs.CreateQueueInput, _ ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	name := aws.ToString(in.QueueName)
+	fifo := strings.HasSuffix(name, ".fifo")
+	if len(name) > 80 || !queueName.MatchString(strings.TrimSuffix(name, ".fifo")) {
+		return nil, apiError("InvalidParameterValue", "Can only include alphanumeric characters, hyphens, or underscores. 1 to 80 in length")
+	}
+	if fifo != (in.Attributes["FifoQueue"] == "true") {
+		return nil, apiError("InvalidParameterValue", "The name of a FIFO queue can only include alphanumeric characters, hyphens, or underscores, must end with .fifo suffix and be 1 to 80 in length")
+	}
+	for name := range in.Attributes {
+		if !settableAttributes[name] {
+			return nil, &sqstypes.InvalidAttributeName{Message: aws.String("Unknown Attribute " + name + ".")}
+		}
+	}
+
+	url := s.b.queueURL(name)
+	if q, ok := s.b.queues[url]; ok {
+		if !equalMaps(q.attrs, in.Attributes) && len(in.Attributes) > 0 {
+			return nil, &sqstypes.QueueNameExists{Message: aws.String("A queue already exists with the same name and a different value for attribute(s)")}
+		}
+		return &sqs.CreateQueueOutput{QueueUrl: aws.String(url)}, nil
+	}
+
+	attrs := copyMap(in.Attributes)
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	s.b.queues[url] = &queue{
+		name:    name,
+		url:     url,
+		arn:     s.b.arn("sqs", name),
+		fifo:    fifo,
+		attrs:   attrs,
+		tags:    copyMap(in.Tags),
+		created: time.Now(),
+		notify:  make(chan struct{}),
+		dedup:   map[string]time.Time{},
+	}
+	s.b.queueOrder = append(s.b.queueOrder, url)
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String(url)}, nil
+}
+
+// GetQueueUrl gives URL of the queue with given name.
+func (s *SQS) GetQueueUrl(_ context.Context, in *sqs.GetQueueUrlInput, _ ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	if owner := aws.ToString(in.QueueOwnerAWSAccountId); owner != "" && owner != s.b.AccountID {
+		return nil, &sqstypes.QueueDoesNotExist{Message: aws.String("The specified queue does not exist for this wsdl version.")}
+	}
+	for _, url := range s.b.queueOrder {
+		if q := s.b.queues[url]; q.name == aws.ToString(in.QueueName) {
+			return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(q.url)}, nil
+		}
+	}
+	return nil, &sqstypes.QueueDoesNotExist{Message: aws.String("The specified queue does not exist for this wsdl version.")}
+}
+
+// DeleteQueue removes queue with all its messages, long polling receivers get QueueDoesNotExist error.
+func (s *SQS) DeleteQueue(_ context.Context, in *sqs.DeleteQueueInput, _ ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.b.queues, q.url)
+	s.b.queueOrder = remove(s.b.queueOrder, q.url)
+	q.changed()
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+// GetQueueAttributes gives queue attributes, including approximate number of messages.
+func (s *SQS) GetQueueAttributes(_ context.Context, in *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	all := q.attributes(time.Now())
+	out := &sqs.GetQueueAttributesOutput{Attributes: map[string]string{}}
+	for _, name := range in.AttributeNames {
+		if name == sqstypes.QueueAttributeNameAll {
+			out.Attributes = all
+			break
+		}
+		value, ok := all[string(name)]
+		if !ok {
+			if settableAttributes[string(name)] {
+				// known, but not set
+				continue
+			}
+			return nil, &sqstypes.InvalidAttributeName{Message: aws.String("Unknown Attribute " + string(name) + ".")}
+		}
+		out.Attributes[string(name)] = value
+	}
+	return out, nil
+}
+
+func (q *queue) attributes(now time.Time) map[string]string {
+	attrs := copyMap(queueDefaults)
+	for k, v := range q.attrs {
+		attrs[k] = v
+	}
+
+	visible, notVisible, delayed := 0, 0, 0
+	for _, m := range q.msgs {
+		switch {
+		case !m.visibleAt.After(now):
+			visible++
+		case m.receiveCount == 0:
+			delayed++
+		default:
+			notVisible++
+		}
+	}
+	attrs["QueueArn"] = q.arn
+	attrs["CreatedTimestamp"] = strconv.FormatInt(q.created.Unix(), 10)
+	attrs["LastModifiedTimestamp"] = strconv.FormatInt(q.created.Unix(), 10)
+	attrs["ApproximateNumberOfMessages"] = strconv.Itoa(visible)
+	attrs["ApproximateNumberOfMessagesNotVisible"] = strconv.Itoa(notVisible)
+	attrs["ApproximateNumberOfMessagesDelayed"] = strconv.Itoa(delayed)
+	return attrs
+}
+
+// SetQueueAttributes changes queue attributes, FifoQueue can't be changed.
+func (s *SQS) SetQueueAttributes(_ context.Context, in *sqs.SetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range in.Attributes {
+		if !settableAttributes[name] {
+			return nil, &sqstypes.InvalidAttributeName{Message: aws.String("Unknown Attribute " + name + ".")}
+		}
+		if name == "FifoQueue" && (value == "true") != q.fifo {
+			return nil, apiError("InvalidAttributeValue", "Invalid value for the parameter FifoQueue. Reason: Modifying queue type is not supported.")
+		}
+		if name == "Policy" && value != "" {
+			if _, err := parseQueuePolicy(value); err != nil {
+				return nil, apiError("InvalidAttributeValue", "Invalid value for the parameter Policy. Reason: %v", err)
+			}
+		}
+	}
+	for name, value := range in.Attributes {
+		q.attrs[name] = value
+	}
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+// ListQueues lists queues in order of creation, optionally by name prefix and paginated when MaxResults is given.
+func (s *SQS) ListQueues(_ context.Context, in *sqs.ListQueuesInput, _ ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	var urls []string
+	for _, url := range s.b.queueOrder {
+		if strings.HasPrefix(s.b.queues[url].name, aws.ToString(in.QueueNamePrefix)) {
+			urls = append(urls, url)
+		}
+	}
+	if in.MaxResults == nil {
+		if len(urls) > 1000 {
+			urls = urls[:1000]
+		}
+		return &sqs.ListQueuesOutput{QueueUrls: urls}, nil
+	}
+
+	size := int(aws.ToInt32(in.MaxResults))
+	if size < 1 || size > 1000 {
+		return nil, apiError("InvalidParameterValue", "Value for parameter MaxResults is invalid. Reason: MaxResults must be an integer between 1 and 1000.")
+	}
+	start, end, next, err := page(len(urls), in.NextToken, size)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.ListQueuesOutput{QueueUrls: urls[start:end], NextToken: next}, nil
+}
+
+// ListQueueTags gives queue tags.
+func (s *SQS) ListQueueTags(_ context.Context, in *sqs.ListQueueTagsInput, _ ...func(*sqs.Options)) (*sqs.ListQueueTagsOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.ListQueueTagsOutput{Tags: copyMap(q.tags)}, nil
+}
+
+// SendMessage puts message into the queue directly, the way SQS producers do.
+func (s *SQS) SendMessage(_ context.Context, in *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.b.send(q, sqstypes.SendMessageBatchRequestEntry{
+		DelaySeconds:           in.DelaySeconds,
+		MessageAttributes:      in.MessageAttributes,
+		MessageBody:            in.MessageBody,
+		MessageDeduplicationId: in.MessageDeduplicationId,
+		MessageGroupId:         in.MessageGroupId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &sqs.SendMessageOutput{MessageId: aws.String(m.id), MD5OfMessageBody: aws.String(md5Hex(m.body))}
+	if q.fifo {
+		out.SequenceNumber = aws.String(m.seq)
+	}
+	return out, nil
+}
+
+// SendMessageBatch puts up to 10 messages into the queue, failures are reported per entry.
+func (s *SQS) SendMessageBatch(_ context.Context, in *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBatch(len(in.Entries), func(i int) string { return aws.ToString(in.Entries[i].Id) }); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range in.Entries {
+		m, err := s.b.send(q, e)
+		if err != nil {
+			out.Failed = append(out.Failed, batchError(e.Id, err))
+			continue
+		}
+		entry := sqstypes.SendMessageBatchResultEntry{Id: e.Id, MessageId: aws.String(m.id), MD5OfMessageBody: aws.String(md5Hex(m.body))}
+		if q.fifo {
+			entry.SequenceNumber = aws.String(m.seq)
+		}
+		out.Successful = append(out.Successful, entry)
+	}
+	return out, nil
+}
+
+func (b *Backend) send(q *queue, in sqstypes.SendMessageBatchRequestEntry) (*message, error) {
+	if aws.ToString(in.MessageBody) == "" {
+		return nil, apiError("MissingParameter", "The request must contain the parameter MessageBody.")
+	}
+	if in.DelaySeconds < 0 || in.DelaySeconds > 900 {
+		return nil, apiError("InvalidParameterValue", "Value %d for parameter DelaySeconds is invalid. Reason: DelaySeconds must be >= 0 and <= 900.", in.DelaySeconds)
+	}
+
+	m := &message{
+		id:    b.nextID(),
+		body:  aws.ToString(in.MessageBody),
+		attrs: in.MessageAttributes,
+		sent:  time.Now(),
+		delay: time.Duration(in.DelaySeconds) * time.Second,
+	}
+	if q.fifo {
+		if in.DelaySeconds != 0 {
+			return nil, apiError("InvalidParameterValue", "Value %d for parameter DelaySeconds is invalid. Reason: The request include parameter that is not valid for this queue type.", in.DelaySeconds)
+		}
+		m.groupID = aws.ToString(in.MessageGroupId)
+		if m.groupID == "" {
+			return nil, apiError("MissingParameter", "The request must contain the parameter MessageGroupId.")
+		}
+		m.dedupID = aws.ToString(in.MessageDeduplicationId)
+		if m.dedupID == "" && q.attrs["ContentBasedDeduplication"] != "true" {
+			return nil, apiError("InvalidParameterValue", "The queue should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+		}
+	}
+	q.enqueue(m)
+	return m, nil
+}
+
+// enqueue adds message to the queue, FIFO duplicates within deduplication interval are dropped.
+func (q *queue) enqueue(m *message) {
+	if q.fifo {
+		if m.dedupID == "" {
+			m.dedupID = md5Hex(m.body)
+		}
+		if seen, ok := q.dedup[m.dedupID]; ok && time.Since(seen) < dedupInterval {
+			return
+		}
+		q.dedup[m.dedupID] = time.Now()
+		q.seq++
+		m.seq = fmt.Sprintf("%020d", q.seq)
+	}
+
+	if m.delay == 0 {
+		delay, _ := strconv.Atoi(q.attrs["DelaySeconds"])
+		m.delay = time.Duration(delay) * time.Second
+	}
+	m.visibleAt = m.sent.Add(m.delay)
+	q.msgs = append(q.msgs, m)
+	q.changed()
+}
+
+// changed wakes up long polling receivers.
+func (q *queue) changed() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// ReceiveMessage receives up to MaxNumberOfMessages messages, long polling for up to WaitTimeSeconds.
+// Received messages stay hidden for VisibilityTimeout, queue default is used when it's zero.
+// FIFO queues don't give messages of a group while its previously received message is in flight.
+func (s *SQS) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	max := int(in.MaxNumberOfMessages)
+	if max == 0 {
+		max = 1
+	}
+	if max < 1 || max > 10 {
+		return nil, apiError("InvalidParameterValue", "Value %d for parameter MaxNumberOfMessages is invalid. Reason: Must be between 1 and 10, if provided.", max)
+	}
+	if in.WaitTimeSeconds < 0 || in.WaitTimeSeconds > 20 {
+		return nil, apiError("InvalidParameterValue", "Value %d for parameter WaitTimeSeconds is invalid. Reason: Must be >= 0 and <= 20, if provided.", in.WaitTimeSeconds)
+	}
+	if in.VisibilityTimeout < 0 || in.VisibilityTimeout > 43200 {
+		return nil, apiError("InvalidParameterValue", "Value %d for parameter VisibilityTimeout is invalid. Reason: Must be >= 0 and <= 43200, if provided.", in.VisibilityTimeout)
+	}
+
+	start := time.Now()
+	for {
+		s.b.mu.Lock()
+		q, err := s.b.queue(in.QueueUrl)
+		if err != nil {
+			s.b.mu.Unlock()
+			return nil, err
+		}
+
+		wait := time.Duration(in.WaitTimeSeconds) * time.Second
+		if in.WaitTimeSeconds == 0 {
+			seconds, _ := strconv.Atoi(q.attrs["ReceiveMessageWaitTimeSeconds"])
+			wait = time.Duration(seconds) * time.Second
+		}
+		visibility := time.Duration(in.VisibilityTimeout) * time.Second
+		if in.VisibilityTimeout == 0 {
+			seconds, _ := strconv.Atoi(q.attrsOrDefault("VisibilityTimeout"))
+			visibility = time.Duration(seconds) * time.Second
+		}
+
+		now := time.Now()
+		msgs := q.receive(now, max, visibility, s.b.nextID)
+		notify, next := q.notify, q.nextVisible(now)
+		s.b.mu.Unlock()
+
+		remaining := wait - time.Since(start)
+		if len(msgs) > 0 || remaining <= 0 {
+			return &sqs.ReceiveMessageOutput{Messages: received(msgs, in.AttributeNames, in.MessageAttributeNames)}, nil
+		}
+
+		if !next.IsZero() && next.Sub(now) < remaining {
+			remaining = next.Sub(now)
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-notify:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+func (q *queue) attrsOrDefault(name string) string {
+	if v, ok := q.attrs[name]; ok {
+		return v
+	}
+	return queueDefaults[name]
+}
+
+// receive picks up to max visible messages and hides them for visibility timeout.
+func (q *queue) receive(now time.Time, max int, visibility time.Duration, nextID func() string) []message {
+	blocked := map[string]bool{}
+	if q.fifo {
+		for _, m := range q.msgs {
+			if m.visibleAt.After(now) && m.receiveCount > 0 {
+				blocked[m.groupID] = true
+			}
+		}
+	}
+
+	var msgs []message
+	for _, m := range q.msgs {
+		if len(msgs) == max {
+			break
+		}
+		if q.fifo && blocked[m.groupID] {
+			continue
+		}
+		if m.visibleAt.After(now) {
+			if q.fifo {
+				// messages of the group have to be received in order
+				blocked[m.groupID] = true
+			}
+			continue
+		}
+
+		m.receiveCount++
+		if m.firstReceive.IsZero() {
+			m.firstReceive = now
+		}
+		m.visibleAt = now.Add(visibility)
+		m.receipt = "receipt-" + nextID()
+		msgs = append(msgs, *m)
+	}
+	return msgs
+}
+
+// nextVisible is time next hidden message becomes visible, zero when there is none.
+func (q *queue) nextVisible(now time.Time) time.Time {
+	var next time.Time
+	for _, m := range q.msgs {
+		if m.visibleAt.After(now) && (next.IsZero() || m.visibleAt.Before(next)) {
+			next = m.visibleAt
+		}
+	}
+	return next
+}
+
+func received(msgs []message, attributeNames []sqstypes.QueueAttributeName, messageAttributeNames []string) []sqstypes.Message {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range attributeNames {
+		wanted[string(name)] = true
+	}
+	all := wanted[string(sqstypes.QueueAttributeNameAll)]
+
+	out := make([]sqstypes.Message, len(msgs))
+	for i, m := range msgs {
+		system := map[string]string{
+			"SenderId":                         DefaultAccountID,
+			"SentTimestamp":                    strconv.FormatInt(m.sent.UnixMilli(), 10),
+			"ApproximateReceiveCount":          strconv.Itoa(m.receiveCount),
+			"ApproximateFirstReceiveTimestamp": strconv.FormatInt(m.firstReceive.UnixMilli(), 10),
+		}
+		if m.groupID != "" {
+			system["MessageGroupId"] = m.groupID
+			system["MessageDeduplicationId"] = m.dedupID
+			system["SequenceNumber"] = m.seq
+		}
+
+		attrs := map[string]string{}
+		for name, value := range system {
+			if all || wanted[name] {
+				attrs[name] = value
+			}
+		}
+		if len(attrs) == 0 {
+			attrs = nil
+		}
+
+		out[i] = sqstypes.Message{
+			MessageId:         aws.String(m.id),
+			ReceiptHandle:     aws.String(m.receipt),
+			Body:              aws.String(m.body),
+			MD5OfBody:         aws.String(md5Hex(m.body)),
+			Attributes:        attrs,
+			MessageAttributes: selectAttributes(m.attrs, messageAttributeNames),
+		}
+	}
+	return out
+}
+
+// selectAttributes picks message attributes by names, 'All', '.*' and 'prefix.*' patterns are supported.
+func selectAttributes(attrs map[string]sqstypes.MessageAttributeValue, names []string) map[string]sqstypes.MessageAttributeValue {
+	selected := map[string]sqstypes.MessageAttributeValue{}
+	for _, name := range names {
+		for attr, value := range attrs {
+			switch {
+			case name == "All" || name == ".*":
+			case strings.HasSuffix(name, ".*") && strings.HasPrefix(attr, strings.TrimSuffix(name, "*")):
+			case name == attr:
+			default:
+				continue
+			}
+			selected[attr] = value
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// DeleteMessage deletes received message. Deleting message that is already gone succeeds, as in real SQS.
+func (s *SQS) DeleteMessage(_ context.Context, in *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.delete(aws.ToString(in.ReceiptHandle)); err != nil {
+		return nil, err
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (q *queue) delete(receipt string) error {
+	if !strings.HasPrefix(receipt, "receipt-") {
+		return &sqstypes.ReceiptHandleIsInvalid{Message: aws.String("The input receipt handle \"" + receipt + "\" is not a valid receipt handle.")}
+	}
+	for i, m := range q.msgs {
+		if m.receipt == receipt {
+			q.msgs = append(q.msgs[:i:i], q.msgs[i+1:]...)
+			q.changed()
+			return nil
+		}
+	}
+	return nil
+}
+
+// DeleteMessageBatch deletes up to 10 received messages, failures are reported per entry.
+func (s *SQS) DeleteMessageBatch(_ context.Context, in *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBatch(len(in.Entries), func(i int) string { return aws.ToString(in.Entries[i].Id) }); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.DeleteMessageBatchOutput{}
+	for _, e := range in.Entries {
+		if err := q.delete(aws.ToString(e.ReceiptHandle)); err != nil {
+			out.Failed = append(out.Failed, batchError(e.Id, err))
+			continue
+		}
+		out.Successful = append(out.Successful, sqstypes.DeleteMessageBatchResultEntry{Id: e.Id})
+	}
+	return out, nil
+}
+
+func validateBatch(n int, id func(int) string) error {
+	if n == 0 {
+		return &sqstypes.EmptyBatchRequest{Message: aws.String("There should be at least one DeleteMessageBatchRequestEntry in the request.")}
+	}
+	if n > 10 {
+		return &sqstypes.TooManyEntriesInBatchRequest{Message: aws.String("Maximum number of entries per request are 10.")}
+	}
+	seen := map[string]bool{}
+	for i := 0; i < n; i++ {
+		if seen[id(i)] {
+			return &sqstypes.BatchEntryIdsNotDistinct{Message: aws.String("Id " + id(i) + " repeated.")}
+		}
+		seen[id(i)] = true
+	}
+	return nil
+}
+
+func batchError(id *string, err error) sqstypes.BatchResultErrorEntry {
+	code, msg := errorCodeAndMessage(err)
+	return sqstypes.BatchResultErrorEntry{Id: id, Code: aws.String(code), Message: aws.String(msg), SenderFault: true}
+}
+
+// ChangeMessageVisibility changes visibility timeout of message in flight, zero makes it visible right away.
+func (s *SQS) ChangeMessageVisibility(_ context.Context, in *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) { //nolint
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	q, err := s.b.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if in.VisibilityTimeout < 0 || in.VisibilityTimeout > 43200 {
+		return nil, apiError("InvalidParameterValue", "Value %d for parameter VisibilityTimeout is invalid. Reason: Must be >= 0 and <= 43200, if provided.", in.VisibilityTimeout)
+	}
+
+	receipt := aws.ToString(in.ReceiptHandle)
+	if !strings.HasPrefix(receipt, "receipt-") {
+		return nil, &sqstypes.ReceiptHandleIsInvalid{Message: aws.String("The input receipt handle \"" + receipt + "\" is not a valid receipt handle.")}
+	}
+	now := time.Now()
+	for _, m := range q.msgs {
+		if m.receipt != receipt {
+			continue
+		}
+		if !m.visibleAt.After(now) {
+			break
+		}
+		m.visibleAt = now.Add(time.Duration(in.VisibilityTimeout) * time.Second)
+		q.changed()
+		return &sqs.ChangeMessageVisibilityOutput{}, nil
+	}
+	return nil, &sqstypes.MessageNotInflight{Message: aws.String("Message does not exist or is not available for visibility timeout change.")}
+}
+
+func (b *Backend) queue(url *string) (*queue, error) {
+	q, ok := b.queues[aws.ToString(url)]
+	if !ok {
+		return nil, &sqstypes.QueueDoesNotExist{Message: aws.String("The specified queue does not exist for this wsdl version.")}
+	}
+	return q, nil
+}
+
+func (b *Backend) queueURL(name string) string {
+	endpoint := strings.TrimSuffix(b.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sqs.%s.amazonaws.com", b.Region)
+	}
+	return fmt.Sprintf("%s/%s/%s", endpoint, b.AccountID, name)
+}
+
+func (b *Backend) queueByArn(arn string) *queue {
+	for _, q := range b.queues {
+		if q.arn == arn {
+			return q
+		}
+	}
+	return nil
+}
+
+// QueueURLs gives URLs of all queues, sorted.
+func (b *Backend) QueueURLs() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	urls := append([]string(nil), b.queueOrder...)
+	sort.Strings(urls)
+	return urls
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec // SQS checksums are MD5
+	return hex.EncodeToString(sum[:])
+}
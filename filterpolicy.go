@@ -0,0 +1,127 @@
+package snstesting
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Filter policy scopes, see https://docs.aws.amazon.com/sns/latest/dg/sns-message-filtering-scope.html.
+const (
+	FilterPolicyScopeMessageAttributes = "MessageAttributes"
+	FilterPolicyScopeMessageBody       = "MessageBody"
+)
+
+// FilterPolicy builds SNS subscription filter policy, to be used with WithFilterPolicy.
+// Every call adds condition to given key, multiple conditions on the same key are OR-ed, different keys are AND-ed.
+// Key holding nested policy can't be given conditions, and the other way round, such policy is invalid.
+// See https://docs.aws.amazon.com/sns/latest/dg/sns-subscription-filter-policies.html for semantics.
+type FilterPolicy struct {
+	rules map[string]interface{}
+	err   error
+}
+
+// NewFilterPolicy creates empty FilterPolicy.
+func NewFilterPolicy() *FilterPolicy {
+	return &FilterPolicy{rules: map[string]interface{}{}}
+}
+
+// Equals matches exact values, strings, numbers, booleans or nil.
+func (p *FilterPolicy) Equals(key string, values ...interface{}) *FilterPolicy {
+	return p.add(key, values...)
+}
+
+// Prefix matches string values starting with prefix.
+func (p *FilterPolicy) Prefix(key, prefix string) *FilterPolicy {
+	return p.add(key, map[string]interface{}{"prefix": prefix})
+}
+
+// Suffix matches string values ending with suffix.
+func (p *FilterPolicy) Suffix(key, suffix string) *FilterPolicy {
+	return p.add(key, map[string]interface{}{"suffix": suffix})
+}
+
+// AnythingBut matches any value except the ones given.
+func (p *FilterPolicy) AnythingBut(key string, values ...interface{}) *FilterPolicy {
+	return p.add(key, map[string]interface{}{"anything-but": values})
+}
+
+// Numeric matches numbers with operator/value pairs, e.g. Numeric("price", ">=", 100, "<", 200).
+func (p *FilterPolicy) Numeric(key string, conditions ...interface{}) *FilterPolicy {
+	return p.add(key, map[string]interface{}{"numeric": conditions})
+}
+
+// Exists matches on presence (or absence) of the key.
+func (p *FilterPolicy) Exists(key string, exists bool) *FilterPolicy {
+	return p.add(key, map[string]interface{}{"exists": exists})
+}
+
+// Nested puts other policy under the key, only meaningful with FilterPolicyScopeMessageBody.
+func (p *FilterPolicy) Nested(key string, nested *FilterPolicy) *FilterPolicy {
+	if _, ok := p.rules[key]; ok {
+		return p.conflict(key)
+	}
+	p.rules[key] = nested
+	return p
+}
+
+// JSON returns JSON representation of the policy, or error when the policy is invalid,
+// e.g. with conflicting keys or values JSON can't represent, like NaN.
+func (p *FilterPolicy) JSON() (string, error) {
+	if err := p.check(); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(p.rules)
+	if err != nil {
+		return "", fmt.Errorf("invalid filter policy: %v", err)
+	}
+	return string(b), nil
+}
+
+// String returns JSON representation of the policy. Invalid policy gives error message instead,
+// which SNS rejects, so it's never mistaken for a policy matching everything. See JSON.
+func (p *FilterPolicy) String() string {
+	s, err := p.JSON()
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
+
+// MarshalJSON implements json.Marshaler, invalid policy gives the same error as JSON.
+func (p *FilterPolicy) MarshalJSON() ([]byte, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(p.rules)
+}
+
+func (p *FilterPolicy) add(key string, conditions ...interface{}) *FilterPolicy {
+	if _, ok := p.rules[key].(*FilterPolicy); ok {
+		return p.conflict(key)
+	}
+	existing, _ := p.rules[key].([]interface{})
+	p.rules[key] = append(existing, conditions...)
+	return p
+}
+
+func (p *FilterPolicy) conflict(key string) *FilterPolicy {
+	if p.err == nil {
+		p.err = fmt.Errorf("invalid filter policy: key %s mixes nested policy with other conditions", key)
+	}
+	return p
+}
+
+// check gives the first error of the policy, or of its nested policies.
+func (p *FilterPolicy) check() error {
+	if p.err != nil {
+		return p.err
+	}
+	for _, rule := range p.rules {
+		if nested, ok := rule.(*FilterPolicy); ok {
+			if err := nested.check(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package snstesting_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/prozz/snstesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterPolicy(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		assert.JSONEq(t, `{}`, snstesting.NewFilterPolicy().String())
+	})
+
+	t.Run("conditions", func(t *testing.T) {
+		policy := snstesting.NewFilterPolicy().
+			Equals("event", "created", "updated").
+			Prefix("region", "eu-").
+			Suffix("file", ".png").
+			AnythingBut("source", "legacy").
+			Numeric("price", ">=", 100, "<", 200).
+			Exists("traceId", true)
+
+		assert.JSONEq(t, `{
+			"event": ["created", "updated"],
+			"region": [{"prefix": "eu-"}],
+			"file": [{"suffix": ".png"}],
+			"source": [{"anything-but": ["legacy"]}],
+			"price": [{"numeric": [">=", 100, "<", 200]}],
+			"traceId": [{"exists": true}]
+		}`, policy.String())
+	})
+
+	t.Run("conditions on the same key are combined", func(t *testing.T) {
+		policy := snstesting.NewFilterPolicy().
+			Equals("event", "created").
+			Prefix("event", "order-")
+
+		assert.JSONEq(t, `{"event": ["created", {"prefix": "order-"}]}`, policy.String())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		policy := snstesting.NewFilterPolicy().
+			Nested("order", snstesting.NewFilterPolicy().Equals("status", "paid"))
+
+		assert.JSONEq(t, `{"order": {"status": ["paid"]}}`, policy.String())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		policy := snstesting.NewFilterPolicy().Equals("event", "created")
+
+		s, err := policy.JSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"event": ["created"]}`, s)
+	})
+
+	t.Run("value JSON can't represent", func(t *testing.T) {
+		policy := snstesting.NewFilterPolicy().Numeric("price", ">", math.NaN())
+
+		_, err := policy.JSON()
+		assert.EqualError(t, err, "invalid filter policy: json: unsupported value: NaN")
+		assert.Equal(t, "invalid filter policy: json: unsupported value: NaN", policy.String())
+	})
+
+	t.Run("nested and conditions on the same key", func(t *testing.T) {
+		nested := snstesting.NewFilterPolicy().Equals("status", "paid")
+
+		for name, policy := range map[string]*snstesting.FilterPolicy{
+			"conditions after nested": snstesting.NewFilterPolicy().Nested("order", nested).Exists("order", true),
+			"nested after conditions": snstesting.NewFilterPolicy().Exists("order", true).Nested("order", nested),
+			"nested twice":            snstesting.NewFilterPolicy().Nested("order", nested).Nested("order", nested),
+			"inside nested": snstesting.NewFilterPolicy().
+				Nested("order", snstesting.NewFilterPolicy().Nested("order", nested).Exists("order", true)),
+		} {
+			_, err := policy.JSON()
+			assert.EqualError(t, err, "invalid filter policy: key order mixes nested policy with other conditions", name)
+
+			_, err = json.Marshal(policy)
+			assert.ErrorContains(t, err, "invalid filter policy: key order mixes nested policy with other conditions", name)
+		}
+	})
+}
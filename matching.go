@@ -0,0 +1,136 @@
+package snstesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrTimeout is returned (wrapped) when expected message does not arrive in time.
+var ErrTimeout = errors.New("timeout waiting for message")
+
+// ErrUnexpectedMessage is returned (wrapped) when message arrives, while none was expected.
+var ErrUnexpectedMessage = errors.New("unexpected message")
+
+// Predicate tells if message is the one test waits for.
+type Predicate func(*Message) bool
+
+// FromTopic matches messages published on given topic, given by ARN or name.
+// Useful when watching multiple topics, see NewMultiSubscriber.
+func FromTopic(topic string) Predicate {
+	return func(msg *Message) bool {
+		return msg.TopicARN == topic || topicNameFromArn(msg.TopicARN) == topic
+	}
+}
+
+// maxSummaryBodyLen limits the length of message bodies in timeout errors.
+const maxSummaryBodyLen = 256
+
+// ReceiveMatching keeps long polling until message satisfying the predicate arrives, or timeout passes.
+// Messages not matching the predicate are kept aside and given to subsequent receive calls, in order of arrival.
+// On timeout ErrTimeout is returned, together with summary of all messages seen while waiting.
+func (s *Subscriber) ReceiveMatching(ctx context.Context, match Predicate, timeout time.Duration) (*Message, error) {
+	deadline := time.Now().Add(timeout)
+
+	seen := s.buffer().snapshot()
+	if m := s.buffer().take(match); m != nil {
+		return m, nil
+	}
+
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, timeoutError(timeout, seen)
+		}
+
+		m, err := s.receiveMessage(ctx, s.waitTimeSecondsWithin(wait))
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			continue
+		}
+		if match(m) {
+			return m, nil
+		}
+
+		seen = append(seen, m)
+		s.buffer().pushBack(m)
+	}
+}
+
+// ExpectNoMessage keeps long polling for the whole time window, making sure no message matching any of predicates arrives.
+// Without predicates, any message is unexpected. Messages already kept aside by previous calls are checked too.
+// Returns ErrUnexpectedMessage together with the offending message body as soon as one arrives.
+// Other messages are kept aside and given to subsequent receive calls, in order of arrival.
+func (s *Subscriber) ExpectNoMessage(ctx context.Context, within time.Duration, match ...Predicate) error {
+	deadline := time.Now().Add(within)
+
+	unexpected := func(m *Message) bool {
+		if len(match) == 0 {
+			return true
+		}
+		for _, fn := range match {
+			if fn(m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if m := s.buffer().take(unexpected); m != nil {
+		return fmt.Errorf("%w: %s", ErrUnexpectedMessage, m.Body)
+	}
+
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil
+		}
+
+		m, err := s.receiveMessage(ctx, s.waitTimeSecondsWithin(wait))
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			continue
+		}
+		if unexpected(m) {
+			return fmt.Errorf("%w: %s", ErrUnexpectedMessage, m.Body)
+		}
+		s.buffer().pushBack(m)
+	}
+}
+
+// waitTimeSecondsWithin turns remaining time into SQS long polling time, so receive calls do not overrun the deadline much.
+// Never goes below a second, to avoid hammering SQS with short polling.
+func (s *Subscriber) waitTimeSecondsWithin(remaining time.Duration) int32 {
+	if limit := s.waitTimeSeconds(); remaining >= time.Duration(limit)*time.Second {
+		return limit
+	}
+	if remaining < time.Second {
+		return 1
+	}
+	return int32(remaining / time.Second)
+}
+
+func timeoutError(timeout time.Duration, seen []*Message) error {
+	if len(seen) == 0 {
+		return fmt.Errorf("%w: nothing arrived within %s", ErrTimeout, timeout)
+	}
+	return fmt.Errorf("%w: no matching message within %s, seen %d: %s", ErrTimeout, timeout, len(seen), summary(seen))
+}
+
+func summary(msgs []*Message) string {
+	v := make([]string, len(msgs))
+	for i, m := range msgs {
+		body := m.Body
+		if len(body) > maxSummaryBodyLen {
+			body = body[:maxSummaryBodyLen] + "..."
+		}
+		v[i] = fmt.Sprintf("[%d] %s", i+1, body)
+	}
+	return strings.Join(v, "; ")
+}
@@ -0,0 +1,239 @@
+package snstesting_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriber_ReceiveMatching(t *testing.T) {
+	ctx := context.Background()
+
+	isB := func(m *snstesting.Message) bool {
+		return m.Message == "b"
+	}
+
+	t.Run("match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		gomock.InOrder(
+			SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String("http://queue.url"),
+				MaxNumberOfMessages: 1,
+				VisibilityTimeout:   3600,
+				WaitTimeSeconds:     3,
+			}).Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "a"}`), ReceiptHandle: aws.String("receipt a")},
+				},
+			}, nil),
+			SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(&sqs.ReceiveMessageOutput{
+					Messages: []sqstypes.Message{
+						{Body: aws.String(`{"Message": "b"}`), ReceiptHandle: aws.String("receipt b")},
+					},
+				}, nil),
+		)
+		SQS.EXPECT().DeleteMessage(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageInput{})).
+			Return(&sqs.DeleteMessageOutput{}, nil).Times(2)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msg, err := subscriber.ReceiveMatching(ctx, isB, 10*time.Second)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "b", msg.Message)
+		}
+
+		// non matching message was kept for later
+		msg, err = subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "a", msg.Message)
+		}
+	})
+
+	t.Run("match kept from previous calls", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+		subscriber.Release(&snstesting.Message{Message: "a"}, &snstesting.Message{Message: "b"})
+
+		msg, err := subscriber.ReceiveMatching(ctx, isB, time.Second)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "b", msg.Message)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "a"}`), ReceiptHandle: aws.String("receipt a")},
+				},
+			}, nil)
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			DoAndReturn(func(ctx context.Context, input *sqs.ReceiveMessageInput, opts ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+				assert.Equal(t, int32(1), input.WaitTimeSeconds)
+				time.Sleep(10 * time.Millisecond)
+				return &sqs.ReceiveMessageOutput{}, nil
+			}).AnyTimes()
+		SQS.EXPECT().DeleteMessage(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageInput{})).
+			Return(&sqs.DeleteMessageOutput{}, nil)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msg, err := subscriber.ReceiveMatching(ctx, isB, 100*time.Millisecond)
+		assert.ErrorIs(t, err, snstesting.ErrTimeout)
+		assert.EqualError(t, err, `timeout waiting for message: no matching message within 100ms, seen 1: [1] {"Message": "a"}`)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(nil, assert.AnError)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		msg, err := subscriber.ReceiveMatching(ctx, isB, time.Second)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, msg)
+	})
+}
+
+func TestSubscriber_ExpectNoMessage(t *testing.T) {
+	ctx := context.Background()
+
+	isB := func(m *snstesting.Message) bool {
+		return m.Message == "b"
+	}
+
+	t.Run("nothing arrived", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			DoAndReturn(func(ctx context.Context, input *sqs.ReceiveMessageInput, opts ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+				time.Sleep(10 * time.Millisecond)
+				return &sqs.ReceiveMessageOutput{}, nil
+			}).MinTimes(1)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		assert.NoError(t, subscriber.ExpectNoMessage(ctx, 50*time.Millisecond))
+	})
+
+	t.Run("unexpected message", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(`{"Message": "a"}`), ReceiptHandle: aws.String("receipt a")},
+				},
+			}, nil)
+		SQS.EXPECT().DeleteMessage(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageInput{})).
+			Return(&sqs.DeleteMessageOutput{}, nil)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		err := subscriber.ExpectNoMessage(ctx, time.Minute)
+		assert.ErrorIs(t, err, snstesting.ErrUnexpectedMessage)
+		assert.EqualError(t, err, `unexpected message: {"Message": "a"}`)
+	})
+
+	t.Run("unexpected message matching predicate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		gomock.InOrder(
+			SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(&sqs.ReceiveMessageOutput{
+					Messages: []sqstypes.Message{
+						{Body: aws.String(`{"Message": "a"}`), ReceiptHandle: aws.String("receipt a")},
+					},
+				}, nil),
+			SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(&sqs.ReceiveMessageOutput{
+					Messages: []sqstypes.Message{
+						{Body: aws.String(`{"Message": "b"}`), ReceiptHandle: aws.String("receipt b")},
+					},
+				}, nil),
+		)
+		SQS.EXPECT().DeleteMessage(ctx, gomock.AssignableToTypeOf(&sqs.DeleteMessageInput{})).
+			Return(&sqs.DeleteMessageOutput{}, nil).Times(2)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		err := subscriber.ExpectNoMessage(ctx, time.Minute, isB)
+		assert.EqualError(t, err, `unexpected message: {"Message": "b"}`)
+
+		// other message was kept for later
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "a", msg.Message)
+		}
+	})
+
+	t.Run("unexpected message kept from previous calls", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+		subscriber.Release(&snstesting.Message{Message: "b", Body: "b"})
+
+		err := subscriber.ExpectNoMessage(ctx, time.Minute, isB)
+		assert.EqualError(t, err, "unexpected message: b")
+	})
+}
+
+func TestFromTopic(t *testing.T) {
+	msg := &snstesting.Message{TopicARN: "arn:aws:sns:eu-west-1:123:orders"}
+
+	assert.True(t, snstesting.FromTopic("orders")(msg))
+	assert.True(t, snstesting.FromTopic("arn:aws:sns:eu-west-1:123:orders")(msg))
+	assert.False(t, snstesting.FromTopic("payments")(msg))
+}
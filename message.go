@@ -0,0 +1,170 @@
+package snstesting
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Message is SNS notification that arrived at ad-hoc SQS queue.
+type Message struct {
+	Type              string
+	MessageID         string
+	TopicARN          string
+	Subject           string
+	Message           string
+	Timestamp         time.Time
+	MessageAttributes map[string]MessageAttribute
+	UnsubscribeURL    string
+	SignatureVersion  string
+	Signature         string
+	SigningCertURL    string
+
+	// MessageGroupID, MessageDeduplicationID and SequenceNumber are set for messages from FIFO topics only.
+	// SequenceNumber is the one given by SNS on publish, with raw message delivery the SQS one is used instead.
+	MessageGroupID         string
+	MessageDeduplicationID string
+	SequenceNumber         string
+
+	// Body is raw SQS message body, as returned by Subscriber.Receive.
+	Body string
+	// ReceiptHandle of SQS message the notification arrived with.
+	ReceiptHandle string
+
+	// deleted tells if message was already deleted from the queue.
+	deleted bool
+}
+
+// MessageAttribute is single SNS message attribute.
+// Type is one of 'String', 'String.Array', 'Number' or 'Binary', possibly followed by custom type suffix.
+type MessageAttribute struct {
+	Type  string
+	Value string
+}
+
+// String returns attribute value as is.
+func (a MessageAttribute) String() string {
+	return a.Value
+}
+
+// Number parses attribute value as a number.
+func (a MessageAttribute) Number() (float64, error) {
+	return strconv.ParseFloat(a.Value, 64)
+}
+
+// StringArray parses attribute value as JSON array of strings.
+func (a MessageAttribute) StringArray() ([]string, error) {
+	var v []string
+	err := json.Unmarshal([]byte(a.Value), &v)
+	return v, err
+}
+
+// Binary decodes base64 encoded attribute value.
+func (a MessageAttribute) Binary() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(a.Value)
+}
+
+// envelope is JSON representation of SNS notification, as delivered to SQS.
+type envelope struct {
+	Type              string                      `json:"Type"`
+	MessageID         string                      `json:"MessageId"`
+	TopicARN          string                      `json:"TopicArn"`
+	Subject           string                      `json:"Subject"`
+	Message           string                      `json:"Message"`
+	Timestamp         string                      `json:"Timestamp"`
+	MessageAttributes map[string]MessageAttribute `json:"MessageAttributes"`
+	UnsubscribeURL    string                      `json:"UnsubscribeURL"`
+	SignatureVersion  string                      `json:"SignatureVersion"`
+	Signature         string                      `json:"Signature"`
+	SigningCertURL    string                      `json:"SigningCertURL"`
+	SequenceNumber    string                      `json:"SequenceNumber"`
+}
+
+func parseMessage(body, receiptHandle string) (*Message, error) {
+	var env envelope
+	if err := json.Unmarshal([]byte(body), &env); err != nil {
+		return nil, fmt.Errorf("parse sns notification failure: %v", err)
+	}
+
+	var ts time.Time
+	if env.Timestamp != "" {
+		var err error
+		ts, err = time.Parse(time.RFC3339Nano, env.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("parse sns notification timestamp failure: %v", err)
+		}
+	}
+
+	return &Message{
+		Type:              env.Type,
+		MessageID:         env.MessageID,
+		TopicARN:          env.TopicARN,
+		Subject:           env.Subject,
+		Message:           env.Message,
+		Timestamp:         ts,
+		MessageAttributes: env.MessageAttributes,
+		UnsubscribeURL:    env.UnsubscribeURL,
+		SignatureVersion:  env.SignatureVersion,
+		Signature:         env.Signature,
+		SigningCertURL:    env.SigningCertURL,
+		SequenceNumber:    env.SequenceNumber,
+		Body:              body,
+		ReceiptHandle:     receiptHandle,
+	}, nil
+}
+
+// rawMessage builds Message out of SQS message delivered with RawMessageDelivery enabled.
+// There is no envelope in such case, so only parts known to SQS are filled in.
+func rawMessage(msg types.Message, topicArn string) (*Message, error) {
+	m := &Message{
+		Type:          "Notification",
+		TopicARN:      topicArn,
+		Message:       aws.ToString(msg.Body),
+		Body:          aws.ToString(msg.Body),
+		ReceiptHandle: aws.ToString(msg.ReceiptHandle),
+	}
+
+	if sent, ok := msg.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)]; ok {
+		millis, err := strconv.ParseInt(sent, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse sqs sent timestamp failure: %v", err)
+		}
+		m.Timestamp = time.UnixMilli(millis).UTC()
+	}
+
+	if len(msg.MessageAttributes) > 0 {
+		m.MessageAttributes = make(map[string]MessageAttribute, len(msg.MessageAttributes))
+		for name, attr := range msg.MessageAttributes {
+			value := aws.ToString(attr.StringValue)
+			if attr.BinaryValue != nil {
+				// keep binary values base64 encoded, the same way SNS envelope does
+				value = base64.StdEncoding.EncodeToString(attr.BinaryValue)
+			}
+			m.MessageAttributes[name] = MessageAttribute{
+				Type:  aws.ToString(attr.DataType),
+				Value: value,
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// setFIFOAttributes fills in FIFO related fields with SQS message system attributes.
+// Sequence number of SNS envelope is kept, it's the one PublishOutput gives.
+func setFIFOAttributes(m *Message, msg types.Message) {
+	if v, ok := msg.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]; ok {
+		m.MessageGroupID = v
+	}
+	if v, ok := msg.Attributes[string(types.MessageSystemAttributeNameMessageDeduplicationId)]; ok {
+		m.MessageDeduplicationID = v
+	}
+	if v, ok := msg.Attributes[string(types.MessageSystemAttributeNameSequenceNumber)]; ok && m.SequenceNumber == "" {
+		m.SequenceNumber = v
+	}
+}
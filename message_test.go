@@ -0,0 +1,48 @@
+package snstesting_test
+
+import (
+	"testing"
+
+	"github.com/prozz/snstesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageAttribute(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		attr := snstesting.MessageAttribute{Type: "String", Value: "foo"}
+		assert.Equal(t, "foo", attr.String())
+	})
+
+	t.Run("number", func(t *testing.T) {
+		attr := snstesting.MessageAttribute{Type: "Number", Value: "12.5"}
+		v, err := attr.Number()
+		assert.NoError(t, err)
+		assert.Equal(t, 12.5, v)
+
+		attr = snstesting.MessageAttribute{Type: "Number", Value: "foo"}
+		_, err = attr.Number()
+		assert.Error(t, err)
+	})
+
+	t.Run("string array", func(t *testing.T) {
+		attr := snstesting.MessageAttribute{Type: "String.Array", Value: `["foo","bar"]`}
+		v, err := attr.StringArray()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo", "bar"}, v)
+
+		attr = snstesting.MessageAttribute{Type: "String.Array", Value: "foo"}
+		_, err = attr.StringArray()
+		assert.Error(t, err)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		attr := snstesting.MessageAttribute{Type: "Binary", Value: "Zm9v"}
+		v, err := attr.Binary()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foo"), v)
+
+		attr = snstesting.MessageAttribute{Type: "Binary", Value: "!"}
+		_, err = attr.Binary()
+		assert.Error(t, err)
+	})
+}
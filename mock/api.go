@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	kms "github.com/aws/aws-sdk-go-v2/service/kms"
 	sns "github.com/aws/aws-sdk-go-v2/service/sns"
 	sqs "github.com/aws/aws-sdk-go-v2/service/sqs"
 	gomock "github.com/golang/mock/gomock"
@@ -36,6 +37,26 @@ func (m *MockSQSAPI) EXPECT() *MockSQSAPIMockRecorder {
 	return m.recorder
 }
 
+// ChangeMessageVisibility mocks base method.
+func (m *MockSQSAPI) ChangeMessageVisibility(arg0 context.Context, arg1 *sqs.ChangeMessageVisibilityInput, arg2 ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ChangeMessageVisibility", varargs...)
+	ret0, _ := ret[0].(*sqs.ChangeMessageVisibilityOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeMessageVisibility indicates an expected call of ChangeMessageVisibility.
+func (mr *MockSQSAPIMockRecorder) ChangeMessageVisibility(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeMessageVisibility", reflect.TypeOf((*MockSQSAPI)(nil).ChangeMessageVisibility), varargs...)
+}
+
 // CreateQueue mocks base method.
 func (m *MockSQSAPI) CreateQueue(arg0 context.Context, arg1 *sqs.CreateQueueInput, arg2 ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
 	m.ctrl.T.Helper()
@@ -56,6 +77,46 @@ func (mr *MockSQSAPIMockRecorder) CreateQueue(arg0, arg1 interface{}, arg2 ...in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateQueue", reflect.TypeOf((*MockSQSAPI)(nil).CreateQueue), varargs...)
 }
 
+// DeleteMessage mocks base method.
+func (m *MockSQSAPI) DeleteMessage(arg0 context.Context, arg1 *sqs.DeleteMessageInput, arg2 ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteMessage", varargs...)
+	ret0, _ := ret[0].(*sqs.DeleteMessageOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMessage indicates an expected call of DeleteMessage.
+func (mr *MockSQSAPIMockRecorder) DeleteMessage(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockSQSAPI)(nil).DeleteMessage), varargs...)
+}
+
+// DeleteMessageBatch mocks base method.
+func (m *MockSQSAPI) DeleteMessageBatch(arg0 context.Context, arg1 *sqs.DeleteMessageBatchInput, arg2 ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteMessageBatch", varargs...)
+	ret0, _ := ret[0].(*sqs.DeleteMessageBatchOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMessageBatch indicates an expected call of DeleteMessageBatch.
+func (mr *MockSQSAPIMockRecorder) DeleteMessageBatch(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessageBatch", reflect.TypeOf((*MockSQSAPI)(nil).DeleteMessageBatch), varargs...)
+}
+
 // DeleteQueue mocks base method.
 func (m *MockSQSAPI) DeleteQueue(arg0 context.Context, arg1 *sqs.DeleteQueueInput, arg2 ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
 	m.ctrl.T.Helper()
@@ -96,6 +157,46 @@ func (mr *MockSQSAPIMockRecorder) GetQueueAttributes(arg0, arg1 interface{}, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueueAttributes", reflect.TypeOf((*MockSQSAPI)(nil).GetQueueAttributes), varargs...)
 }
 
+// ListQueueTags mocks base method.
+func (m *MockSQSAPI) ListQueueTags(arg0 context.Context, arg1 *sqs.ListQueueTagsInput, arg2 ...func(*sqs.Options)) (*sqs.ListQueueTagsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListQueueTags", varargs...)
+	ret0, _ := ret[0].(*sqs.ListQueueTagsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListQueueTags indicates an expected call of ListQueueTags.
+func (mr *MockSQSAPIMockRecorder) ListQueueTags(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListQueueTags", reflect.TypeOf((*MockSQSAPI)(nil).ListQueueTags), varargs...)
+}
+
+// ListQueues mocks base method.
+func (m *MockSQSAPI) ListQueues(arg0 context.Context, arg1 *sqs.ListQueuesInput, arg2 ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListQueues", varargs...)
+	ret0, _ := ret[0].(*sqs.ListQueuesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListQueues indicates an expected call of ListQueues.
+func (mr *MockSQSAPIMockRecorder) ListQueues(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListQueues", reflect.TypeOf((*MockSQSAPI)(nil).ListQueues), varargs...)
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockSQSAPI) ReceiveMessage(arg0 context.Context, arg1 *sqs.ReceiveMessageInput, arg2 ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
 	m.ctrl.T.Helper()
@@ -159,6 +260,66 @@ func (m *MockSNSAPI) EXPECT() *MockSNSAPIMockRecorder {
 	return m.recorder
 }
 
+// GetSubscriptionAttributes mocks base method.
+func (m *MockSNSAPI) GetSubscriptionAttributes(arg0 context.Context, arg1 *sns.GetSubscriptionAttributesInput, arg2 ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetSubscriptionAttributes", varargs...)
+	ret0, _ := ret[0].(*sns.GetSubscriptionAttributesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionAttributes indicates an expected call of GetSubscriptionAttributes.
+func (mr *MockSNSAPIMockRecorder) GetSubscriptionAttributes(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionAttributes", reflect.TypeOf((*MockSNSAPI)(nil).GetSubscriptionAttributes), varargs...)
+}
+
+// GetTopicAttributes mocks base method.
+func (m *MockSNSAPI) GetTopicAttributes(arg0 context.Context, arg1 *sns.GetTopicAttributesInput, arg2 ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetTopicAttributes", varargs...)
+	ret0, _ := ret[0].(*sns.GetTopicAttributesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopicAttributes indicates an expected call of GetTopicAttributes.
+func (mr *MockSNSAPIMockRecorder) GetTopicAttributes(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopicAttributes", reflect.TypeOf((*MockSNSAPI)(nil).GetTopicAttributes), varargs...)
+}
+
+// ListSubscriptions mocks base method.
+func (m *MockSNSAPI) ListSubscriptions(arg0 context.Context, arg1 *sns.ListSubscriptionsInput, arg2 ...func(*sns.Options)) (*sns.ListSubscriptionsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListSubscriptions", varargs...)
+	ret0, _ := ret[0].(*sns.ListSubscriptionsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubscriptions indicates an expected call of ListSubscriptions.
+func (mr *MockSNSAPIMockRecorder) ListSubscriptions(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptions", reflect.TypeOf((*MockSNSAPI)(nil).ListSubscriptions), varargs...)
+}
+
 // ListTopics mocks base method.
 func (m *MockSNSAPI) ListTopics(arg0 context.Context, arg1 *sns.ListTopicsInput, arg2 ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
 	m.ctrl.T.Helper()
@@ -179,6 +340,26 @@ func (mr *MockSNSAPIMockRecorder) ListTopics(arg0, arg1 interface{}, arg2 ...int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTopics", reflect.TypeOf((*MockSNSAPI)(nil).ListTopics), varargs...)
 }
 
+// Publish mocks base method.
+func (m *MockSNSAPI) Publish(arg0 context.Context, arg1 *sns.PublishInput, arg2 ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Publish", varargs...)
+	ret0, _ := ret[0].(*sns.PublishOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockSNSAPIMockRecorder) Publish(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockSNSAPI)(nil).Publish), varargs...)
+}
+
 // Subscribe mocks base method.
 func (m *MockSNSAPI) Subscribe(arg0 context.Context, arg1 *sns.SubscribeInput, arg2 ...func(*sns.Options)) (*sns.SubscribeOutput, error) {
 	m.ctrl.T.Helper()
@@ -218,3 +399,66 @@ func (mr *MockSNSAPIMockRecorder) Unsubscribe(arg0, arg1 interface{}, arg2 ...in
 	varargs := append([]interface{}{arg0, arg1}, arg2...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsubscribe", reflect.TypeOf((*MockSNSAPI)(nil).Unsubscribe), varargs...)
 }
+
+// MockKMSAPI is a mock of KMSAPI interface.
+type MockKMSAPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockKMSAPIMockRecorder
+}
+
+// MockKMSAPIMockRecorder is the mock recorder for MockKMSAPI.
+type MockKMSAPIMockRecorder struct {
+	mock *MockKMSAPI
+}
+
+// NewMockKMSAPI creates a new mock instance.
+func NewMockKMSAPI(ctrl *gomock.Controller) *MockKMSAPI {
+	mock := &MockKMSAPI{ctrl: ctrl}
+	mock.recorder = &MockKMSAPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKMSAPI) EXPECT() *MockKMSAPIMockRecorder {
+	return m.recorder
+}
+
+// DescribeKey mocks base method.
+func (m *MockKMSAPI) DescribeKey(arg0 context.Context, arg1 *kms.DescribeKeyInput, arg2 ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeKey", varargs...)
+	ret0, _ := ret[0].(*kms.DescribeKeyOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeKey indicates an expected call of DescribeKey.
+func (mr *MockKMSAPIMockRecorder) DescribeKey(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeKey", reflect.TypeOf((*MockKMSAPI)(nil).DescribeKey), varargs...)
+}
+
+// GetKeyPolicy mocks base method.
+func (m *MockKMSAPI) GetKeyPolicy(arg0 context.Context, arg1 *kms.GetKeyPolicyInput, arg2 ...func(*kms.Options)) (*kms.GetKeyPolicyOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetKeyPolicy", varargs...)
+	ret0, _ := ret[0].(*kms.GetKeyPolicyOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKeyPolicy indicates an expected call of GetKeyPolicy.
+func (mr *MockKMSAPIMockRecorder) GetKeyPolicy(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeyPolicy", reflect.TypeOf((*MockKMSAPI)(nil).GetKeyPolicy), varargs...)
+}
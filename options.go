@@ -0,0 +1,204 @@
+package snstesting
+
+import "time"
+
+// Option changes Config used by New and NewSubscriber when creating ad-hoc resources.
+type Option func(*Config)
+
+// WithTopicMatch changes the way topic name is compared with existing topics, exact matching is used by default.
+func WithTopicMatch(match TopicMatch) Option {
+	return func(c *Config) {
+		c.TopicMatch = match
+	}
+}
+
+// WithTopicResolver changes the way topic name is turned into topic ARN.
+// Topic ARNs are always used as is, other names are looked up with ListTopicsResolver by default.
+func WithTopicResolver(r TopicResolver) Option {
+	return func(c *Config) {
+		c.TopicResolver = r
+	}
+}
+
+// WithRawMessageDelivery enables RawMessageDelivery on ad-hoc subscription, so messages arrive without SNS envelope.
+// Subscriber.ReceiveMessage takes message attributes from SQS message attributes in such case.
+func WithRawMessageDelivery() Option {
+	return func(c *Config) {
+		c.RawMessageDelivery = true
+	}
+}
+
+// WithFilterPolicy sets FilterPolicy on ad-hoc subscription, policy is JSON string, see FilterPolicy for a builder.
+// Messages that would be filtered out by the policy in production will never arrive in the test either.
+func WithFilterPolicy(policy string) Option {
+	return func(c *Config) {
+		c.FilterPolicy = policy
+	}
+}
+
+// WithFilterPolicyScope sets FilterPolicyScope on ad-hoc subscription,
+// either FilterPolicyScopeMessageAttributes (SNS default) or FilterPolicyScopeMessageBody.
+func WithFilterPolicyScope(scope string) Option {
+	return func(c *Config) {
+		c.FilterPolicyScope = scope
+	}
+}
+
+// WithFIFO forces FIFO ad-hoc queue, FIFO topics (with '.fifo' suffix) are detected automatically anyway.
+func WithFIFO() Option {
+	return func(c *Config) {
+		c.FIFO = true
+	}
+}
+
+// WithManualAck keeps received messages in ad-hoc queue, hidden, until explicitly acknowledged.
+// See Subscriber.Ack, Subscriber.Nack and Subscriber.Release.
+func WithManualAck() Option {
+	return func(c *Config) {
+		c.ManualAck = true
+	}
+}
+
+// WithDecoder sets Decoder used to decode message payloads, JSON is used by default.
+func WithDecoder(d Decoder) Option {
+	return func(c *Config) {
+		c.Decoder = d
+	}
+}
+
+// WithQueueNamePrefix changes prefix of ad-hoc queue name, 'snstesting_' by default.
+// Random suffix is always appended, so parallel tests don't collide.
+func WithQueueNamePrefix(prefix string) Option {
+	return func(c *Config) {
+		c.QueueNamePrefix = prefix
+	}
+}
+
+// WithWaitTime changes long polling time of single receive call, 3 seconds by default, up to 20 seconds.
+func WithWaitTime(d time.Duration) Option {
+	return func(c *Config) {
+		c.WaitTime = d
+	}
+}
+
+// WithVisibilityTimeout changes for how long received messages stay hidden in the queue, an hour by default.
+// Matters with WithManualAck only, as messages are deleted on receipt otherwise.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.VisibilityTimeout = d
+	}
+}
+
+// WithMessageRetentionPeriod changes for how long SQS keeps messages in ad-hoc queue, SQS default is 4 days.
+func WithMessageRetentionPeriod(d time.Duration) Option {
+	return func(c *Config) {
+		c.MessageRetentionPeriod = d
+	}
+}
+
+// WithKMSMasterKey enables server side encryption of ad-hoc queue with given KMS key ID, ARN or alias.
+// Note that the key policy has to allow SNS to use it, otherwise messages are silently dropped.
+func WithKMSMasterKey(keyID string) Option {
+	return func(c *Config) {
+		c.KMSMasterKeyID = keyID
+	}
+}
+
+// WithTopicKMSKey enables server side encryption of ad-hoc queue with the KMS key the topic is encrypted with,
+// all topics have to share the key. Handy when production topics and queues use the same customer managed key.
+func WithTopicKMSKey() Option {
+	return func(c *Config) {
+		c.TopicKMSKey = true
+	}
+}
+
+// WithSSE enables server side encryption of ad-hoc queue with SQS owned key (SSE-SQS), SNS needs no extra permissions.
+// It's for organizations requiring encryption of every queue, see WithKMSMasterKey to use KMS key instead.
+func WithSSE() Option {
+	return func(c *Config) {
+		c.SSE = true
+	}
+}
+
+// WithKMS lets NewSubscriber check that KMS key of ad-hoc queue is enabled and its key policy allows SNS
+// to use it, so misconfiguration fails early instead of messages being silently dropped.
+// NewTestSubscriber does it with KMS client of given AWS configuration, built only when KMS key is used.
+func WithKMS(KMS KMSAPI) Option {
+	return func(c *Config) {
+		c.KMS = KMS
+	}
+}
+
+// WithQueueTags sets tags on ad-hoc queue, calling it again adds more tags.
+func WithQueueTags(tags map[string]string) Option {
+	return func(c *Config) {
+		c.QueueTags = mergeMaps(c.QueueTags, tags)
+	}
+}
+
+// WithQueueAttributes sets any attributes on ad-hoc queue, calling it again adds more attributes.
+// Attributes controlled by other options, like FifoQueue, take precedence.
+func WithQueueAttributes(attrs map[string]string) Option {
+	return func(c *Config) {
+		c.QueueAttributes = mergeMaps(c.QueueAttributes, attrs)
+	}
+}
+
+// WithSubscriptionAttributes sets any attributes on ad-hoc subscription, e.g. DeliveryPolicy.
+// Calling it again adds more attributes. Attributes controlled by other options, like FilterPolicy, take precedence.
+func WithSubscriptionAttributes(attrs map[string]string) Option {
+	return func(c *Config) {
+		c.SubscriptionAttributes = mergeMaps(c.SubscriptionAttributes, attrs)
+	}
+}
+
+func mergeMaps(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// WithOwner sets owner tag of ad-hoc queue, shown by Sweep. By default it's $USER@hostname.
+func WithOwner(owner string) Option {
+	return func(c *Config) {
+		c.Owner = owner
+	}
+}
+
+// WithCleanupTimeout bounds cleanup run after the test by test helpers, like New, a minute by default.
+// Throttled and transient failures are retried until it passes.
+func WithCleanupTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.CleanupTimeout = d
+	}
+}
+
+// WithLeakLedger makes Cleanup append resources it failed to remove to given file, one JSON line each,
+// so they can be removed later with Sweeper.SweepLedger or 'snstesting sweep -ledger'.
+func WithLeakLedger(path string) Option {
+	return func(c *Config) {
+		c.LeakLedger = path
+	}
+}
+
+// WithReadinessProbe makes NewSubscriber wait, up to given timeout, until ad-hoc subscriptions are confirmed,
+// so messages published right after it returns are not lost. See WithCanary for stronger guarantee.
+func WithReadinessProbe(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.ReadinessTimeout = timeout
+	}
+}
+
+// WithCanary makes readiness probe also publish uniquely marked canary message on every topic and wait until it
+// arrives in ad-hoc queue. Canaries are never received by the test, neither are canaries of other subscribers.
+// Readiness probe is enabled with 30 seconds timeout, unless WithReadinessProbe is used.
+// Note that other subscribers of the topic get canaries as well, and that it can't be used with filter policy.
+func WithCanary() Option {
+	return func(c *Config) {
+		c.Canary = true
+	}
+}
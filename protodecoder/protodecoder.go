@@ -0,0 +1,44 @@
+// Package protodecoder decodes protobuf payloads of SNS messages, see snstesting.WithDecoder.
+// It's kept apart from snstesting, so the root package doesn't import protobuf.
+package protodecoder
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/prozz/snstesting"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protobuf decodes binary protobuf payload, v has to be proto.Message or a pointer to one (allocated when nil).
+// As SNS messages are text, protobuf payloads usually come base64 encoded,
+// use snstesting.Chain(snstesting.Base64(), Protobuf()) then.
+func Protobuf() snstesting.Decoder {
+	return snstesting.DecoderFunc(func(payload []byte, v interface{}) error {
+		m, ok := v.(proto.Message)
+		if !ok {
+			m, ok = allocProtoMessage(v)
+		}
+		if !ok {
+			return fmt.Errorf("protobuf: %T is not proto.Message", v)
+		}
+		if err := proto.Unmarshal(payload, m); err != nil {
+			return fmt.Errorf("protobuf: %v", err)
+		}
+		return nil
+	})
+}
+
+// allocProtoMessage handles v being a pointer to proto.Message, e.g. **pb.Order, as used by snstesting.Receiver[*pb.Order].
+func allocProtoMessage(v interface{}) (proto.Message, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Ptr {
+		return nil, false
+	}
+	elem := rv.Elem()
+	if elem.IsNil() {
+		elem.Set(reflect.New(elem.Type().Elem()))
+	}
+	m, ok := elem.Interface().(proto.Message)
+	return m, ok
+}
@@ -0,0 +1,38 @@
+package protodecoder_test
+
+import (
+	"testing"
+
+	"github.com/prozz/snstesting/protodecoder"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtobuf(t *testing.T) {
+	t.Run("protobuf", func(t *testing.T) {
+		payload, err := proto.Marshal(wrapperspb.String("hello"))
+		assert.NoError(t, err)
+
+		v := &wrapperspb.StringValue{}
+		err = protodecoder.Protobuf().Decode(payload, v)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", v.GetValue())
+	})
+
+	t.Run("protobuf into nil pointer", func(t *testing.T) {
+		payload, err := proto.Marshal(wrapperspb.String("hello"))
+		assert.NoError(t, err)
+
+		var v *wrapperspb.StringValue
+		err = protodecoder.Protobuf().Decode(payload, &v)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", v.GetValue())
+	})
+
+	t.Run("protobuf into non proto message", func(t *testing.T) {
+		var v struct{ ID string }
+		err := protodecoder.Protobuf().Decode(nil, &v)
+		assert.EqualError(t, err, "protobuf: *struct { ID string } is not proto.Message")
+	})
+}
@@ -0,0 +1,185 @@
+package snstesting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	// defaultReadinessTimeout bounds readiness probe enabled with WithCanary only.
+	defaultReadinessTimeout = 30 * time.Second
+	// readinessPollInterval is delay between checks of pending subscription.
+	readinessPollInterval = 250 * time.Millisecond
+	// canaryPrefix marks canary messages, so they are told apart from messages of the test, also of other subscribers.
+	canaryPrefix = "snstesting:canary:"
+	// canaryGroupID is message group of canaries published on FIFO topics, so they don't hold up groups of the test.
+	canaryGroupID = "snstesting-canary"
+)
+
+// readinessTimeout bounds readiness probe, zero when it's off.
+func (s *Subscriber) readinessTimeout() time.Duration {
+	if s.Config.ReadinessTimeout == 0 && s.Config.Canary {
+		return defaultReadinessTimeout
+	}
+	return s.Config.ReadinessTimeout
+}
+
+// waitReady polls ad-hoc subscriptions until they're confirmed. With canary enabled it also publishes canary
+// message on every topic and waits until all of them arrive, proving the queue policy is in effect.
+func (s *Subscriber) waitReady(ctx context.Context) error {
+	timeout := s.readinessTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, sub := range s.Config.Subscriptions {
+		if err := s.waitConfirmed(ctx, sub.SubscriptionARN); err != nil {
+			return fmt.Errorf("subscription %s not ready within %s: %v", sub.SubscriptionARN, timeout, err)
+		}
+	}
+	if !s.Config.Canary {
+		return nil
+	}
+	if err := s.waitCanaries(ctx); err != nil {
+		return fmt.Errorf("canary not delivered within %s: %v", timeout, err)
+	}
+	return nil
+}
+
+// waitConfirmed polls subscription attributes until subscription is no longer pending confirmation.
+// Subscription not found yet is polled as well, as it may take a moment to propagate.
+func (s *Subscriber) waitConfirmed(ctx context.Context, subscriptionARN string) error {
+	for {
+		out, err := s.SNS.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{
+			SubscriptionArn: aws.String(subscriptionARN),
+		})
+		if err == nil && out.Attributes["PendingConfirmation"] != "true" {
+			return nil
+		}
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+
+		sleep(ctx, readinessPollInterval)
+		if ctx.Err() != nil {
+			if err != nil {
+				return fmt.Errorf("%v (%v)", ctx.Err(), err)
+			}
+			return fmt.Errorf("%v (pending confirmation)", ctx.Err())
+		}
+	}
+}
+
+// waitCanaries publishes canary message on every topic and receives them from the queue.
+// Other messages received meanwhile are kept hidden until all canaries arrive, so they don't hold canaries up,
+// and are made visible again then, for the test to receive them.
+func (s *Subscriber) waitCanaries(ctx context.Context) error {
+	pending := map[string]bool{}
+	for _, sub := range s.Config.Subscriptions {
+		canary := canaryPrefix + s.Config.QueueName + ":" + rndString(20)
+		in := &sns.PublishInput{
+			TopicArn: aws.String(sub.TopicARN),
+			Message:  aws.String(canary),
+		}
+		if s.Config.FIFO {
+			in.MessageGroupId = aws.String(canaryGroupID)
+			in.MessageDeduplicationId = aws.String(canary[len(canaryPrefix):])
+		}
+		if _, err := s.SNS.Publish(ctx, in); err != nil {
+			return fmt.Errorf("publish canary on %s failure: %v", sub.TopicARN, err)
+		}
+		pending[canary] = true
+	}
+
+	var others []string
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		out, err := s.SQS.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.Config.QueueURL),
+			MaxNumberOfMessages: maxBatchSize,
+			WaitTimeSeconds:     1,
+			VisibilityTimeout:   hiddenUntil(ctx),
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Messages) == 0 {
+			sleep(ctx, readinessPollInterval)
+			continue
+		}
+
+		var canaries []string
+		for _, msg := range out.Messages {
+			if canary, ok := canaryOf(msg); ok {
+				delete(pending, canary)
+				canaries = append(canaries, aws.ToString(msg.ReceiptHandle))
+			} else {
+				others = append(others, aws.ToString(msg.ReceiptHandle))
+			}
+		}
+		if len(canaries) > 0 {
+			if _, err := s.deleteMessages(ctx, canaries); err != nil {
+				return fmt.Errorf("delete canary failure: %v", err)
+			}
+		}
+	}
+
+	for _, receiptHandle := range others {
+		_, err := s.SQS.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String(s.Config.QueueURL),
+			ReceiptHandle:     aws.String(receiptHandle),
+			VisibilityTimeout: 0,
+		})
+		if err != nil {
+			return fmt.Errorf("release message failure: %v", err)
+		}
+	}
+	return nil
+}
+
+// hiddenUntil gives visibility timeout keeping messages received by readiness probe hidden until its deadline.
+func hiddenUntil(ctx context.Context) int32 {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return int32(time.Until(deadline)/time.Second) + 1
+}
+
+// canaryOf tells if SQS message is a canary, either raw or wrapped in SNS notification, and gives its content.
+func canaryOf(msg types.Message) (string, bool) {
+	body := aws.ToString(msg.Body)
+	if strings.HasPrefix(body, canaryPrefix) || !strings.Contains(body, canaryPrefix) {
+		return body, strings.HasPrefix(body, canaryPrefix)
+	}
+	var notification struct {
+		Message string
+	}
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return "", false
+	}
+	return notification.Message, strings.HasPrefix(notification.Message, canaryPrefix)
+}
+
+// withoutCanaries splits received messages into messages of the test and receipt handles of canaries.
+func withoutCanaries(msgs []types.Message) ([]types.Message, []string) {
+	var kept []types.Message
+	var canaries []string
+	for _, msg := range msgs {
+		if _, ok := canaryOf(msg); ok {
+			canaries = append(canaries, aws.ToString(msg.ReceiptHandle))
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept, canaries
+}
@@ -0,0 +1,203 @@
+package snstesting_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// probedSNS is fake SNS with subscriptions pending confirmation for a while.
+type probedSNS struct {
+	*fake.SNS
+
+	// pending is number of checks subscriptions are pending confirmation for, -1 for ever
+	pending int
+	// beforePublish is called before every publish
+	beforePublish func()
+	// dropPublish makes published messages disappear
+	dropPublish bool
+}
+
+func (s *probedSNS) GetSubscriptionAttributes(ctx context.Context, in *sns.GetSubscriptionAttributesInput, opts ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error) { //nolint
+	out, err := s.SNS.GetSubscriptionAttributes(ctx, in, opts...)
+	if err == nil && s.pending != 0 {
+		s.pending--
+		out.Attributes["PendingConfirmation"] = "true"
+	}
+	return out, err
+}
+
+func (s *probedSNS) Publish(ctx context.Context, in *sns.PublishInput, opts ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	if s.beforePublish != nil {
+		s.beforePublish()
+	}
+	if s.dropPublish {
+		return &sns.PublishOutput{MessageId: aws.String("dropped")}, nil
+	}
+	return s.SNS.Publish(ctx, in, opts...)
+}
+
+func publishOn(t *testing.T, backend *fake.Backend, topicArn, message string) {
+	t.Helper()
+
+	_, err := backend.SNS().Publish(context.Background(), &sns.PublishInput{TopicArn: aws.String(topicArn), Message: aws.String(message)})
+	assert.NoError(t, err)
+}
+
+func TestNewSubscriber_Readiness(t *testing.T) {
+	ctx := context.Background()
+	fastPolling := snstesting.WithWaitTime(time.Second)
+
+	t.Run("canary", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		other, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", fastPolling)
+		assert.NoError(t, err)
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders", fastPolling, snstesting.WithCanary())
+		assert.NoError(t, err)
+		assert.Empty(t, backend.Messages(s.Config.QueueURL), "canary received")
+		assert.Len(t, backend.Messages(other.Config.QueueURL), 1, "canary of other subscriber")
+
+		publishOn(t, backend, topicArn, "hello")
+
+		msg, err := other.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg, "canary skipped") {
+			assert.Equal(t, "hello", msg.Message)
+		}
+		msg, err = s.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "hello", msg.Message)
+		}
+		assert.Empty(t, backend.Messages(other.Config.QueueURL), "canary deleted")
+	})
+
+	t.Run("canary on multiple FIFO topics with raw delivery", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders.fifo")
+		backend.CreateTopic("payments.fifo")
+
+		s, err := snstesting.NewMultiSubscriber(ctx, backend.SNS(), backend.SQS(), []string{"orders.fifo", "payments.fifo"},
+			fastPolling, snstesting.WithCanary())
+		assert.NoError(t, err)
+		assert.Empty(t, backend.Messages(s.Config.QueueURL))
+
+		raw, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders.fifo",
+			fastPolling, snstesting.WithRawMessageDelivery(), snstesting.WithCanary())
+		assert.NoError(t, err)
+		assert.Empty(t, backend.Messages(raw.Config.QueueURL))
+	})
+
+	t.Run("messages received meanwhile are kept", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		SNS := &probedSNS{SNS: backend.SNS()}
+		SNS.beforePublish = func() {
+			SNS.beforePublish = nil
+			publishOn(t, backend, topicArn, "early")
+		}
+		s, err := snstesting.NewSubscriber(ctx, SNS, backend.SQS(), "orders", fastPolling, snstesting.WithCanary())
+		assert.NoError(t, err)
+
+		got, err := s.Receive(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, got, "early")
+	})
+
+	t.Run("canary behind full batch of messages", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		SNS := &probedSNS{SNS: backend.SNS()}
+		SNS.beforePublish = func() {
+			SNS.beforePublish = nil
+			for i := 0; i < 15; i++ {
+				publishOn(t, backend, topicArn, fmt.Sprintf("early %d", i))
+			}
+		}
+		start := time.Now()
+		s, err := snstesting.NewSubscriber(ctx, SNS, backend.SQS(), "orders", fastPolling,
+			snstesting.WithCanary(), snstesting.WithReadinessProbe(3*time.Second))
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), 3*time.Second)
+
+		msgs, err := s.ReceiveBatch(ctx, 20)
+		assert.NoError(t, err)
+		assert.Len(t, msgs, 15, "messages received meanwhile are kept")
+	})
+
+	t.Run("canary never arrives", func(t *testing.T) {
+		backend := fake.New()
+		topicArn := backend.CreateTopic("orders")
+
+		SNS := &probedSNS{SNS: backend.SNS(), dropPublish: true}
+		SNS.beforePublish = func() {
+			publishOn(t, backend, topicArn, "early")
+		}
+		start := time.Now()
+		_, err := snstesting.NewSubscriber(ctx, SNS, backend.SQS(), "orders", fastPolling,
+			snstesting.WithCanary(), snstesting.WithReadinessProbe(time.Second))
+		assert.ErrorContains(t, err, "canary not delivered within 1s: context deadline exceeded")
+		assert.Less(t, time.Since(start), 3*time.Second)
+	})
+
+	t.Run("pending confirmation", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		_, err := snstesting.NewSubscriber(ctx, &probedSNS{SNS: backend.SNS(), pending: 2}, backend.SQS(), "orders",
+			snstesting.WithReadinessProbe(5*time.Second))
+		assert.NoError(t, err)
+
+		_, err = snstesting.NewSubscriber(ctx, &probedSNS{SNS: backend.SNS(), pending: -1}, backend.SQS(), "orders",
+			snstesting.WithReadinessProbe(500*time.Millisecond))
+		assert.ErrorContains(t, err, "pending confirmation")
+		assert.Len(t, backend.QueueURLs(), 1, "ad-hoc resources of failed probe are cleaned up")
+	})
+
+	t.Run("canary with filter policy", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		_, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders",
+			snstesting.WithCanary(), snstesting.WithFilterPolicy(`{"event": ["created"]}`))
+		assert.EqualError(t, err, "canary can't be used with filter policy, it would be filtered out")
+		assert.Empty(t, backend.QueueURLs())
+	})
+
+	t.Run("receive of canaries only is bounded by wait time", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		// canaries of other subscribers keep coming
+		SQS.EXPECT().ReceiveMessage(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+			time.Sleep(100 * time.Millisecond)
+			return &sqs.ReceiveMessageOutput{Messages: []sqstypes.Message{{
+				Body:          aws.String("snstesting:canary:other:123"),
+				ReceiptHandle: aws.String("handle"),
+			}}}, nil
+		}).MinTimes(2)
+		SQS.EXPECT().DeleteMessage(ctx, gomock.Any()).Return(&sqs.DeleteMessageOutput{}, nil).MinTimes(2)
+
+		s := snstesting.Subscriber{SQS: SQS, Config: snstesting.Config{QueueURL: "http://queue.url", WaitTime: time.Second}}
+		start := time.Now()
+		msg, err := s.Receive(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, msg)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
@@ -3,10 +3,14 @@ package snstesting
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
-	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -17,11 +21,15 @@ import (
 
 // Subscriber for checking what arrives at any SNS topic in an integration testing setting.
 // It does it through temporary SQS queue and ad-hoc subscription that may be easily cleaned up after the test.
-// Temporary queues are prefixed with 'snstesting'.
+// Temporary queues are prefixed with 'snstesting_' by default, see WithQueueNamePrefix.
 type Subscriber struct {
 	SNS    SNSAPI
 	SQS    SQSAPI
 	Config Config
+
+	// shared by copies of Subscriber, as Receive, ReceiveMessage and Cleanup have value receivers
+	pending *messageBuffer
+	streams *streamSet
 }
 
 // Config describes both temporarily generated and existing resources used by the ad-hoc SNS checking mechanism.
@@ -32,54 +40,174 @@ type Config struct {
 	QueueURL        string
 	QueueARN        string
 	SubscriptionARN string
+
+	// TopicMatch tells how TopicName is looked up among existing topics.
+	TopicMatch TopicMatch
+	// TopicResolver turns TopicName into TopicARN, see WithTopicResolver.
+	TopicResolver TopicResolver
+	// RawMessageDelivery makes SNS deliver messages without notification envelope, see WithRawMessageDelivery.
+	RawMessageDelivery bool
+	// FilterPolicy of ad-hoc subscription, see WithFilterPolicy.
+	FilterPolicy string
+	// FilterPolicyScope of ad-hoc subscription, see WithFilterPolicyScope.
+	FilterPolicyScope string
+	// FIFO makes ad-hoc queue a FIFO queue, required by FIFO topics. Enabled automatically for '.fifo' topics.
+	// Note that SQS does not deliver next message of the group until previously received one is acknowledged.
+	FIFO bool
+	// ManualAck disables deleting messages from ad-hoc queue on receipt, see WithManualAck.
+	ManualAck bool
+	// Decoder decodes message payloads, see WithDecoder.
+	Decoder Decoder
+	// QueueNamePrefix of ad-hoc queue, 'snstesting_' by default, see WithQueueNamePrefix.
+	QueueNamePrefix string
+	// WaitTime is long polling time of single receive call, 3 seconds by default, see WithWaitTime.
+	WaitTime time.Duration
+	// VisibilityTimeout of received messages, an hour by default, see WithVisibilityTimeout.
+	VisibilityTimeout time.Duration
+	// MessageRetentionPeriod of ad-hoc queue, SQS default is used when zero, see WithMessageRetentionPeriod.
+	MessageRetentionPeriod time.Duration
+	// KMSMasterKeyID encrypts ad-hoc queue with given KMS key, see WithKMSMasterKey.
+	KMSMasterKeyID string
+	// TopicKMSKey encrypts ad-hoc queue with KMS key of the topic, see WithTopicKMSKey.
+	TopicKMSKey bool
+	// SSE encrypts ad-hoc queue with SQS owned key, see WithSSE.
+	SSE bool
+	// KMS checks that SNS may use KMS key of ad-hoc queue, see WithKMS. Set by NewTestSubscriber when KMS key is used.
+	KMS KMSAPI
+	// QueueTags are set on ad-hoc queue, see WithQueueTags.
+	QueueTags map[string]string
+	// QueueAttributes are set on ad-hoc queue, see WithQueueAttributes.
+	QueueAttributes map[string]string
+	// SubscriptionAttributes are set on ad-hoc subscription, see WithSubscriptionAttributes.
+	SubscriptionAttributes map[string]string
+	// Owner of ad-hoc queue, tagged for Sweep reports. Taken from environment by default, see WithOwner.
+	// NewSubscriber sets it to the owner actually tagged.
+	Owner string
+	// TestName that created ad-hoc queue, tagged for Sweep reports. Set by NewTestSubscriber.
+	TestName string
+	// ReadinessTimeout bounds readiness probe run by NewSubscriber, the probe is off when zero, see WithReadinessProbe.
+	ReadinessTimeout time.Duration
+	// Canary makes readiness probe publish canary message on every topic and wait for it, see WithCanary.
+	Canary bool
+	// CleanupTimeout bounds cleanup run after the test, a minute by default, see WithCleanupTimeout.
+	CleanupTimeout time.Duration
+	// LeakLedger is file recording resources cleanup failed to remove, for Sweeper, see WithLeakLedger.
+	LeakLedger string
+	// Subscriptions of ad-hoc queue, one per topic. TopicName, TopicARN and SubscriptionARN describe the first one.
+	Subscriptions []Subscription
+
+	// newKMS gives KMS client for KMS, only built when ad-hoc queue is encrypted with KMS key.
+	newKMS func() KMSAPI
+}
+
+// Subscription of ad-hoc queue to SNS topic.
+type Subscription struct {
+	TopicName       string
+	TopicARN        string
+	SubscriptionARN string
 }
 
+const (
+	// defaultWaitTimeSeconds is long polling time of single receive call.
+	defaultWaitTimeSeconds = 3
+	// defaultVisibilityTimeoutSeconds hides received messages for long enough to be acknowledged manually.
+	defaultVisibilityTimeoutSeconds = 3600
+	// defaultQueueNamePrefix is prefix of ad-hoc queue names.
+	defaultQueueNamePrefix = "snstesting_"
+)
+
 // ReceiveFn checks for message that arrived at SNS (via ad-hoc SQS queue), can be called repeatedly.
 type ReceiveFn func() string
 
+// ReceiveMessageFn checks for parsed SNS notification (via ad-hoc SQS queue), can be called repeatedly.
+// Returns nil when nothing arrived.
+type ReceiveMessageFn func() *Message
+
 // New creates Subscriber for testing purposes based on provided AWS configuration.
 // Ad-hoc resources are cleaned up after the test automatically with use of t.Cleanup.
 // In case of an error, t.Fatal is executed.
-// In case more control is needed over Subscriber, or it's Config, please use NewSubscriber.
-func New(t *testing.T, cfg aws.Config, topicName string) ReceiveFn {
+// In case more control is needed over Subscriber, or it's Config, please use NewTestSubscriber or NewSubscriber.
+func New(t TB, cfg aws.Config, topicName string, opts ...Option) ReceiveFn {
 	t.Helper()
+	return NewTestSubscriber(t, cfg, topicName, opts...).Receive
+}
 
-	ctx := context.Background()
+// NewMessageReceiver works exactly like New, but returned function gives parsed SNS notifications instead of raw strings.
+func NewMessageReceiver(t TB, cfg aws.Config, topicName string, opts ...Option) ReceiveMessageFn {
+	t.Helper()
+	return NewTestSubscriber(t, cfg, topicName, opts...).ReceiveMessage
+}
 
-	SNS := sns.NewFromConfig(cfg)
-	SQS := sqs.NewFromConfig(cfg)
+// NewSubscriber creates Subscriber instance for ad-hoc subscribing to SNS topic.
+// Topic may be given by ARN, which is used as is, or by name.
+// By default topicName has to be exactly the same as the name of existing topic, see WithTopicMatch for alternatives.
+// See WithTopicResolver for other ways of resolving topic names.
+func NewSubscriber(ctx context.Context, SNS SNSAPI, SQS SQSAPI, topicName string, opts ...Option) (Subscriber, error) {
+	return newSubscriber(ctx, SNS, SQS, []string{topicName}, opts...)
+}
 
-	s, err := NewSubscriber(ctx, SNS, SQS, topicName)
-	if err != nil {
-		t.Fatal(err)
+// NewMultiSubscriber creates Subscriber watching multiple SNS topics through single ad-hoc queue, so cross-topic
+// order of messages is kept and setup is faster. Source topic of every message is given by Message.TopicARN.
+// Topics are resolved the same way as in NewSubscriber. Raw message delivery can't be used, as it drops the source
+// topic information, and FIFO topics can't be mixed with standard ones.
+func NewMultiSubscriber(ctx context.Context, SNS SNSAPI, SQS SQSAPI, topicNames []string, opts ...Option) (Subscriber, error) {
+	if len(topicNames) == 0 {
+		return Subscriber{}, errors.New("no topics given")
 	}
+	return newSubscriber(ctx, SNS, SQS, topicNames, opts...)
+}
 
-	t.Cleanup(func() {
-		err := s.Cleanup(ctx)
-		if err != nil {
-			t.Fatal(err)
-		}
-	})
+func newSubscriber(ctx context.Context, SNS SNSAPI, SQS SQSAPI, topicNames []string, opts ...Option) (Subscriber, error) {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
 
-	return func() string {
-		msg, err := s.Receive(ctx)
-		if err != nil {
-			t.Fatal(err)
-		}
-		return msg
+	if config.FilterPolicy != "" && !json.Valid([]byte(config.FilterPolicy)) {
+		return Subscriber{}, fmt.Errorf("invalid filter policy: %s", config.FilterPolicy)
+	}
+	if err := validateQueueConfig(config); err != nil {
+		return Subscriber{}, err
+	}
+	if config.Canary && config.FilterPolicy != "" {
+		return Subscriber{}, errors.New("canary can't be used with filter policy, it would be filtered out")
+	}
+	if config.RawMessageDelivery && len(topicNames) > 1 {
+		return Subscriber{}, errors.New("raw message delivery can't be used with multiple topics, source topic would be unknown")
 	}
-}
 
-// NewSubscriber creates Subscriber instance for ad-hoc subscribing to SNS topic.
-func NewSubscriber(ctx context.Context, SNS SNSAPI, SQS SQSAPI, topicName string) (Subscriber, error) {
-	topicArn, err := findTopicArn(ctx, SNS, topicName)
+	topicArns, err := resolveTopics(ctx, SNS, topicNames, config)
 	if err != nil {
 		return Subscriber{}, err
 	}
 
-	testingQueueName := fmt.Sprintf("snstesting_%s", rndString(20))
+	fifo := 0
+	for _, topicArn := range topicArns {
+		if strings.HasSuffix(topicArn, ".fifo") {
+			fifo++
+		}
+	}
+	if fifo > 0 && fifo < len(topicArns) {
+		return Subscriber{}, fmt.Errorf("fifo and standard topics can't share a queue: %s", strings.Join(topicArns, ", "))
+	}
+	if fifo > 0 {
+		config.FIFO = true
+	}
+
+	if err := checkEncryption(ctx, SNS, topicArns, &config); err != nil {
+		return Subscriber{}, err
+	}
+
+	testingQueueName := queueNamePrefix(config) + rndString(20)
+	if config.FIFO {
+		testingQueueName += ".fifo"
+	}
+
+	tags := queueTags(config, time.Now())
 	createQueueOutput, err := SQS.CreateQueue(ctx, &sqs.CreateQueueInput{
-		QueueName: aws.String(testingQueueName),
+		QueueName:  aws.String(testingQueueName),
+		Attributes: queueAttributes(config),
+		Tags:       tags,
 	})
 	if err != nil {
 		return Subscriber{}, err
@@ -96,14 +224,10 @@ func NewSubscriber(ctx context.Context, SNS SNSAPI, SQS SQSAPI, topicName string
 
 	queueArn := queueAttrsOutput.Attributes[string(types.QueueAttributeNameQueueArn)]
 
-	policy := policyTemplate
-	policy = strings.ReplaceAll(policy, "<<TOPIC_ARN>>", topicArn)
-	policy = strings.ReplaceAll(policy, "<<QUEUE_ARN>>", queueArn)
-
 	_, err = SQS.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
 		QueueUrl: createQueueOutput.QueueUrl,
 		Attributes: map[string]string{
-			string(types.QueueAttributeNamePolicy): policy,
+			string(types.QueueAttributeNamePolicy): queuePolicy(queueArn, topicArns),
 		},
 	})
 	if err != nil {
@@ -111,50 +235,413 @@ func NewSubscriber(ctx context.Context, SNS SNSAPI, SQS SQSAPI, topicName string
 			combineErr(err, cleanupQueue(ctx, SQS, *createQueueOutput.QueueUrl)))
 	}
 
-	subscribeOutput, err := SNS.Subscribe(ctx, &sns.SubscribeInput{
-		Protocol: aws.String("sqs"),
-		TopicArn: aws.String(topicArn),
-		Endpoint: aws.String(queueArn),
-	})
-	if err != nil {
-		return Subscriber{}, fmt.Errorf("subscribe failure: %v",
-			combineErr(err, cleanupQueue(ctx, SQS, *createQueueOutput.QueueUrl)))
-	}
-
-	return Subscriber{
-		SNS: SNS,
-		SQS: SQS,
-		Config: Config{
-			TopicName:       topicName,
+	var subscriptions []Subscription
+	for _, topicArn := range topicArns {
+		subscribeOutput, err := SNS.Subscribe(ctx, &sns.SubscribeInput{
+			Protocol:   aws.String("sqs"),
+			TopicArn:   aws.String(topicArn),
+			Endpoint:   aws.String(queueArn),
+			Attributes: subscriptionAttributes(config),
+		})
+		if err != nil {
+			errs := []error{err}
+			for _, sub := range subscriptions {
+				errs = append(errs, unsubscribe(ctx, SNS, sub.SubscriptionARN))
+			}
+			errs = append(errs, cleanupQueue(ctx, SQS, *createQueueOutput.QueueUrl))
+			return Subscriber{}, fmt.Errorf("subscribe failure: %v", combineErr(errs...))
+		}
+		subscriptions = append(subscriptions, Subscription{
+			TopicName:       topicNameFromArn(topicArn),
 			TopicARN:        topicArn,
-			QueueName:       testingQueueName,
-			QueueURL:        *createQueueOutput.QueueUrl,
-			QueueARN:        queueArn,
 			SubscriptionARN: *subscribeOutput.SubscriptionArn,
-		},
-	}, nil
+		})
+	}
+
+	config.TopicName = subscriptions[0].TopicName
+	config.TopicARN = subscriptions[0].TopicARN
+	config.QueueName = testingQueueName
+	// owner actually tagged, leaks are reported with it
+	config.Owner = tags[TagOwner]
+	config.QueueURL = *createQueueOutput.QueueUrl
+	config.QueueARN = queueArn
+	config.SubscriptionARN = subscriptions[0].SubscriptionARN
+	config.Subscriptions = subscriptions
+
+	s := Subscriber{
+		SNS:     SNS,
+		SQS:     SQS,
+		Config:  config,
+		pending: &messageBuffer{},
+		streams: &streamSet{},
+	}
+	if s.readinessTimeout() > 0 {
+		if err := s.waitReady(ctx); err != nil {
+			return Subscriber{}, combineErr(err, s.Cleanup(ctx))
+		}
+	}
+	return s, nil
+}
+
+// resolveTopics turns topic names into ARNs, duplicates are skipped.
+func resolveTopics(ctx context.Context, SNS SNSAPI, topicNames []string, config Config) ([]string, error) {
+	var topicArns []string
+	seen := map[string]bool{}
+	for _, topicName := range topicNames {
+		resolver := config.TopicResolver
+		switch {
+		case isTopicArn(topicName):
+			resolver = ARNResolver{}
+		case resolver == nil:
+			resolver = ListTopicsResolver{SNS: SNS, Match: config.TopicMatch}
+		}
+
+		topicArn, err := resolver.ResolveTopic(ctx, topicName)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[topicArn] {
+			seen[topicArn] = true
+			topicArns = append(topicArns, topicArn)
+		}
+	}
+	return topicArns, nil
 }
 
 // Receive receives single message that was published on SNS.
+// Message is deleted from the queue right away, unless WithManualAck is used.
 func (s Subscriber) Receive(ctx context.Context) (string, error) {
-	receiveOut, err := s.SQS.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-		QueueUrl:            aws.String(s.Config.QueueURL),
-		MaxNumberOfMessages: 1,
-		VisibilityTimeout:   3600, // just hide msg for long enough, could be moved to Config for easy manipulation
-		WaitTimeSeconds:     3,
-	})
-	if err != nil {
+	if m := s.buffer().pop(); m != nil {
+		return m.Body, nil
+	}
+
+	msgs, err := s.receive(ctx, 1, s.waitTimeSeconds())
+	if err != nil || len(msgs) == 0 {
 		return "", err
 	}
-	if len(receiveOut.Messages) > 0 {
-		return *receiveOut.Messages[0].Body, nil
+	return aws.ToString(msgs[0].Body), nil
+}
+
+// ReceiveMessage receives single message that was published on SNS and parses its SNS notification envelope.
+// With raw message delivery there is no envelope, Message is built from SQS message and its attributes instead.
+// Message is deleted from the queue right away, unless WithManualAck is used, see Ack, Nack and Release.
+// Returns nil Message when nothing arrived.
+func (s Subscriber) ReceiveMessage(ctx context.Context) (*Message, error) {
+	if m := s.buffer().pop(); m != nil {
+		return m, nil
+	}
+	return s.receiveMessage(ctx, s.waitTimeSeconds())
+}
+
+func (s *Subscriber) receiveMessage(ctx context.Context, waitTimeSeconds int32) (*Message, error) {
+	msgs, err := s.receiveMessages(ctx, 1, waitTimeSeconds)
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	return msgs[0], nil
+}
+
+// receiveMessages gets up to max parsed messages from the queue.
+// In case of parsing failure, messages parsed fine are kept aside for subsequent receive calls.
+func (s *Subscriber) receiveMessages(ctx context.Context, max, waitTimeSeconds int32) ([]*Message, error) {
+	msgs, err := s.receive(ctx, max, waitTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed []*Message
+	var errs []error
+	for _, msg := range msgs {
+		m, err := s.toMessage(msg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		parsed = append(parsed, m)
+	}
+	if len(errs) > 0 {
+		s.buffer().pushBack(parsed...)
+		return nil, combineErr(errs...)
+	}
+	return parsed, nil
+}
+
+func (s *Subscriber) toMessage(msg types.Message) (*Message, error) {
+	var m *Message
+	var err error
+	if s.Config.RawMessageDelivery {
+		m, err = rawMessage(msg, s.Config.TopicARN)
+	} else {
+		m, err = parseMessage(aws.ToString(msg.Body), aws.ToString(msg.ReceiptHandle))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.Config.FIFO {
+		setFIFOAttributes(m, msg)
+	}
+	m.deleted = !s.Config.ManualAck
+	return m, nil
+}
+
+// receive gets up to max messages from the queue, deleting them unless manual acknowledgement is on.
+func (s *Subscriber) receive(ctx context.Context, max, waitTimeSeconds int32) ([]types.Message, error) {
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(s.Config.QueueURL),
+		MaxNumberOfMessages: max,
+		VisibilityTimeout:   s.visibilityTimeoutSeconds(),
+		WaitTimeSeconds:     waitTimeSeconds,
+	}
+	if s.Config.RawMessageDelivery {
+		// with raw delivery SNS message attributes become SQS message attributes
+		input.MessageAttributeNames = []string{"All"}
+		input.AttributeNames = append(input.AttributeNames,
+			types.QueueAttributeName(types.MessageSystemAttributeNameSentTimestamp))
+	}
+	if s.Config.FIFO {
+		input.AttributeNames = append(input.AttributeNames,
+			types.QueueAttributeName(types.MessageSystemAttributeNameMessageGroupId),
+			types.QueueAttributeName(types.MessageSystemAttributeNameMessageDeduplicationId),
+			types.QueueAttributeName(types.MessageSystemAttributeNameSequenceNumber))
+	}
+
+	deadline := time.Now().Add(time.Duration(waitTimeSeconds) * time.Second)
+	var msgs []types.Message
+	for {
+		receiveOut, err := s.SQS.ReceiveMessage(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		// canaries of readiness probes, also of other subscribers of the topic, are never given to the test
+		var canaries []string
+		msgs, canaries = withoutCanaries(receiveOut.Messages)
+		if len(canaries) == 0 {
+			break
+		}
+		if _, err := s.deleteMessages(ctx, canaries); err != nil {
+			return nil, fmt.Errorf("delete canary failure: %v", err)
+		}
+		// messages of the test may be waiting right behind, they are waited for within the rest of the wait time
+		remaining := time.Until(deadline)
+		if len(msgs) > 0 || remaining <= 0 || ctx.Err() != nil {
+			break
+		}
+		input.WaitTimeSeconds = int32((remaining + time.Second - 1) / time.Second)
+	}
+	if len(msgs) == 0 || s.Config.ManualAck {
+		return msgs, nil
 	}
-	return "", nil
+
+	receiptHandles := make([]string, len(msgs))
+	for i, msg := range msgs {
+		receiptHandles[i] = aws.ToString(msg.ReceiptHandle)
+	}
+	if _, err := s.deleteMessages(ctx, receiptHandles); err != nil {
+		return nil, fmt.Errorf("delete message failure: %v", err)
+	}
+	return msgs, nil
 }
 
-// Cleanup unsubscribes temporary SQS queue from SNS and removes it.
+// Cleanup unsubscribes temporary SQS queue from SNS and removes it. Running streams are stopped first.
+// Throttled and transient failures are retried with backoff until ctx is done, and removal is verified.
+// Resources left behind are reported with *LeakError, and recorded in Config.LeakLedger when it's set.
+// Removal which could not be confirmed, for lack of permissions or as deleted queue is still visible, is not a leak.
 func (s Subscriber) Cleanup(ctx context.Context) error {
-	return combineErr(unsubscribe(ctx, s.SNS, s.Config.SubscriptionARN), cleanupQueue(ctx, s.SQS, s.Config.QueueURL))
+	_, err := s.cleanup(ctx)
+	return err
+}
+
+// cleanup works like Cleanup, also giving notes on resources removed, but not confirmed gone.
+func (s *Subscriber) cleanup(ctx context.Context) ([]string, error) {
+	s.stopStreams()
+
+	var leaks []Leak
+	var notes []string
+	for _, subscriptionARN := range s.subscriptionARNs() {
+		if err := removeSubscription(ctx, s.SNS, subscriptionARN); unverified(err) {
+			notes = append(notes, err.Error())
+		} else if err != nil {
+			leaks = append(leaks, Leak{SubscriptionARN: subscriptionARN, Reason: err.Error()})
+		}
+	}
+	if err := removeQueue(ctx, s.SQS, s.Config.QueueURL); unverified(err) {
+		notes = append(notes, err.Error())
+	} else if err != nil {
+		leaks = append(leaks, Leak{QueueURL: s.Config.QueueURL, Reason: err.Error()})
+	}
+	return notes, s.leaked(leaks)
+}
+
+// subscriptionARNs gives all ad-hoc subscriptions, falling back to Config.SubscriptionARN for hand-made Config.
+func (s *Subscriber) subscriptionARNs() []string {
+	if len(s.Config.Subscriptions) == 0 {
+		return []string{s.Config.SubscriptionARN}
+	}
+	var arns []string
+	for _, sub := range s.Config.Subscriptions {
+		arns = append(arns, sub.SubscriptionARN)
+	}
+	return arns
+}
+
+func subscriptionAttributes(config Config) map[string]string {
+	attrs := copyMap(config.SubscriptionAttributes)
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	if config.RawMessageDelivery {
+		attrs["RawMessageDelivery"] = "true"
+	}
+	if config.FilterPolicy != "" {
+		attrs["FilterPolicy"] = config.FilterPolicy
+	}
+	if config.FilterPolicyScope != "" {
+		attrs["FilterPolicyScope"] = config.FilterPolicyScope
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// waitTimeSeconds is long polling time of single receive call.
+func (s *Subscriber) waitTimeSeconds() int32 {
+	if s.Config.WaitTime == 0 {
+		return defaultWaitTimeSeconds
+	}
+	return int32(s.Config.WaitTime / time.Second)
+}
+
+func (s *Subscriber) visibilityTimeoutSeconds() int32 {
+	if s.Config.VisibilityTimeout == 0 {
+		return defaultVisibilityTimeoutSeconds
+	}
+	return int32(s.Config.VisibilityTimeout / time.Second)
+}
+
+func queueNamePrefix(config Config) string {
+	if config.QueueNamePrefix == "" {
+		return defaultQueueNamePrefix
+	}
+	return config.QueueNamePrefix
+}
+
+// queueTags of ad-hoc queue, snstesting tags can't be overridden with WithQueueTags.
+func queueTags(config Config, now time.Time) map[string]string {
+	tags := copyMap(config.QueueTags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	tags[TagCreatedAt] = now.UTC().Format(time.RFC3339)
+	tags[TagOwner] = tagValue(config.Owner)
+	if config.Owner == "" {
+		tags[TagOwner] = tagValue(defaultOwner())
+	}
+	if config.TestName != "" {
+		tags[TagTestName] = tagValue(config.TestName)
+	}
+	return tags
+}
+
+// tagValue truncates value to SQS tag value limit, which counts characters, not bytes.
+func tagValue(v string) string {
+	if r := []rune(v); len(r) > 256 {
+		return string(r[:256])
+	}
+	return v
+}
+
+// defaultOwner is user running the tests, possibly on remote host.
+func defaultOwner() string {
+	owner := os.Getenv("USER")
+	if owner == "" {
+		owner = "unknown"
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		owner += "@" + host
+	}
+	return owner
+}
+
+// queueAttributes of ad-hoc queue, nil when there are none.
+func queueAttributes(config Config) map[string]string {
+	attrs := copyMap(config.QueueAttributes)
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	if config.FIFO {
+		attrs[string(types.QueueAttributeNameFifoQueue)] = "true"
+	}
+	if config.MessageRetentionPeriod != 0 {
+		attrs[string(types.QueueAttributeNameMessageRetentionPeriod)] = strconv.Itoa(int(config.MessageRetentionPeriod / time.Second))
+	}
+	if config.KMSMasterKeyID != "" {
+		attrs[string(types.QueueAttributeNameKmsMasterKeyId)] = config.KMSMasterKeyID
+	}
+	if config.SSE {
+		attrs[string(types.QueueAttributeNameSqsManagedSseEnabled)] = "true"
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// validateQueueConfig checks queue and polling settings against SQS limits, so mistakes are reported up front.
+func validateQueueConfig(config Config) error {
+	var errs []error
+	if prefix := config.QueueNamePrefix; prefix != "" {
+		// 20 random characters and '.fifo' suffix have to fit in 80 characters limit
+		if len(prefix) > 55 {
+			errs = append(errs, fmt.Errorf("queue name prefix too long, up to 55 characters allowed: %s", prefix))
+		}
+		if !validQueueName.MatchString(prefix) {
+			errs = append(errs, fmt.Errorf("queue name prefix may contain alphanumeric characters, hyphens and underscores only: %s", prefix))
+		}
+	}
+	if d := config.WaitTime; d != 0 && (d < time.Second || d > 20*time.Second) {
+		errs = append(errs, fmt.Errorf("wait time has to be between 1s and 20s: %s", d))
+	}
+	if d := config.VisibilityTimeout; d != 0 && (d < time.Second || d > 12*time.Hour) {
+		errs = append(errs, fmt.Errorf("visibility timeout has to be between 1s and 12h: %s", d))
+	}
+	if d := config.MessageRetentionPeriod; d != 0 && (d < time.Minute || d > 14*24*time.Hour) {
+		errs = append(errs, fmt.Errorf("message retention period has to be between 1m and 336h: %s", d))
+	}
+	if config.SSE && (config.KMSMasterKeyID != "" || config.TopicKMSKey) {
+		errs = append(errs, errors.New("SSE-SQS and KMS key can't be used together, pick one of them"))
+	}
+	if len(errs) > 0 {
+		return combineErr(errs...)
+	}
+	return nil
+}
+
+var validQueueName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func copyMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	v := make(map[string]string, len(m))
+	for k, val := range m {
+		v[k] = val
+	}
+	return v
+}
+
+// queuePolicy allows SNS topics to send messages to the queue.
+func queuePolicy(queueArn string, topicArns []string) string {
+	var sourceArn interface{} = topicArns
+	if len(topicArns) == 1 {
+		sourceArn = topicArns[0]
+	}
+	source, _ := json.Marshal(sourceArn)
+
+	policy := policyTemplate
+	policy = strings.ReplaceAll(policy, `"<<TOPIC_ARN>>"`, string(source))
+	policy = strings.ReplaceAll(policy, "<<QUEUE_ARN>>", queueArn)
+	return policy
 }
 
 func cleanupQueue(ctx context.Context, SQS SQSAPI, queueURL string) error {
@@ -171,32 +658,6 @@ func unsubscribe(ctx context.Context, SNS SNSAPI, subscriptionARN string) error
 	return err
 }
 
-// iterating over all sns topics may be a waste of time
-// according to stackoverflow its ok create topic with same name again to get ARN
-// TODO explore if this is best practice, risk: some sns props may get overwritten by accident?
-func findTopicArn(ctx context.Context, cli SNSAPI, topicName string) (string, error) {
-	var nextToken *string
-	for {
-		out, err := cli.ListTopics(ctx, &sns.ListTopicsInput{
-			NextToken: nextToken,
-		})
-		if err != nil {
-			return "", err
-		}
-
-		for _, topic := range out.Topics {
-			if topic.TopicArn != nil && strings.Contains(*topic.TopicArn, topicName) {
-				return *topic.TopicArn, nil
-			}
-		}
-
-		nextToken = out.NextToken
-		if nextToken == nil {
-			return "", fmt.Errorf("topic %s not found", topicName)
-		}
-	}
-}
-
 // simple random string generation to avoid external deps
 func rndString(n int) string {
 	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
@@ -13,6 +14,7 @@ import (
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/golang/mock/gomock"
 	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/fake"
 	"github.com/prozz/snstesting/mock"
 	"github.com/stretchr/testify/assert"
 )
@@ -57,6 +59,61 @@ func TestNewSubscriber(t *testing.T) {
 		assert.Empty(t, subscriber)
 	})
 
+	t.Run("no topic found, similar topics listed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: nil}).
+			Return(&sns.ListTopicsOutput{
+				Topics: []snstypes.Topic{
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders-dlq")},
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:payments")},
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:legacy-orders")},
+				},
+			}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "orders")
+		assert.EqualError(t, err, "topic orders not found, similar topics: "+
+			"arn:aws:sns:eu-west-1:123456789012:orders-dlq, arn:aws:sns:eu-west-1:123456789012:legacy-orders")
+		assert.Empty(t, subscriber)
+	})
+
+	t.Run("ambiguous topic pattern", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: nil}).
+			Return(&sns.ListTopicsOutput{
+				NextToken: aws.String("page 2"),
+				Topics: []snstypes.Topic{
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders")},
+				},
+			}, nil)
+		SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: aws.String("page 2")}).
+			Return(&sns.ListTopicsOutput{
+				Topics: []snstypes.Topic{
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders-dlq")},
+				},
+			}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "orders*", snstesting.WithTopicMatch(snstesting.MatchGlob))
+		assert.EqualError(t, err, "topic pattern orders* is ambiguous, matching topics: "+
+			"arn:aws:sns:eu-west-1:123456789012:orders, arn:aws:sns:eu-west-1:123456789012:orders-dlq")
+		assert.Empty(t, subscriber)
+	})
+
+	t.Run("invalid topic pattern", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "orders(", snstesting.WithTopicMatch(snstesting.MatchRegexp))
+		assert.Error(t, err)
+		assert.Empty(t, subscriber)
+	})
+
 	t.Run("error creating queue", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		SQS := mock.NewMockSQSAPI(ctrl)
@@ -432,6 +489,300 @@ func TestNewSubscriber(t *testing.T) {
 		assert.Equal(t, "arn:foo:bar:subscription", subscriber.Config.SubscriptionARN)
 	})
 
+	t.Run("success, exact topic match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: nil}).
+			Return(&sns.ListTopicsOutput{
+				Topics: []snstypes.Topic{
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders-dlq")},
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders")},
+				},
+			}, nil)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "orders")
+		assert.NoError(t, err)
+		assert.Equal(t, "orders", subscriber.Config.TopicName)
+		assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", subscriber.Config.TopicARN)
+	})
+
+	t.Run("success, topic arn given", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123456789012:orders")
+		assert.NoError(t, err)
+		assert.Equal(t, "orders", subscriber.Config.TopicName)
+		assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", subscriber.Config.TopicARN)
+	})
+
+	t.Run("success, topic arn given with resolver", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123456789012:orders",
+			snstesting.WithTopicResolver(snstesting.StaticResolver{}))
+		assert.NoError(t, err)
+		assert.Equal(t, "orders", subscriber.Config.TopicName)
+		assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", subscriber.Config.TopicARN)
+	})
+
+	t.Run("error resolving topic", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "orders",
+			snstesting.WithTopicResolver(snstesting.StaticResolver{}))
+		assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+		assert.Empty(t, subscriber)
+	})
+
+	t.Run("success, glob topic match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: nil}).
+			Return(&sns.ListTopicsOutput{
+				Topics: []snstypes.Topic{
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders-dlq")},
+					{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders-v2")},
+				},
+			}, nil)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders-v2"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "orders-v*", snstesting.WithTopicMatch(snstesting.MatchGlob))
+		assert.NoError(t, err)
+		assert.Equal(t, "orders-v2", subscriber.Config.TopicName)
+		assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders-v2", subscriber.Config.TopicARN)
+		assert.Equal(t, snstesting.MatchGlob, subscriber.Config.TopicMatch)
+	})
+
+	t.Run("success, raw message delivery", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+			Attributes: map[string]string{
+				"RawMessageDelivery": "true",
+			},
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123456789012:orders",
+			snstesting.WithRawMessageDelivery())
+		assert.NoError(t, err)
+		assert.True(t, subscriber.Config.RawMessageDelivery)
+	})
+
+	t.Run("invalid filter policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123456789012:orders",
+			snstesting.WithFilterPolicy(`{"event": [`))
+		assert.EqualError(t, err, `invalid filter policy: {"event": [`)
+		assert.Empty(t, subscriber)
+	})
+
+	t.Run("success, filter policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+			Attributes: map[string]string{
+				"FilterPolicy":      `{"status":["paid"]}`,
+				"FilterPolicyScope": "MessageBody",
+			},
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123456789012:orders",
+			snstesting.WithFilterPolicy(snstesting.NewFilterPolicy().Equals("status", "paid").String()),
+			snstesting.WithFilterPolicyScope(snstesting.FilterPolicyScopeMessageBody))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"status":["paid"]}`, subscriber.Config.FilterPolicy)
+		assert.Equal(t, "MessageBody", subscriber.Config.FilterPolicyScope)
+	})
+
+	t.Run("success, fifo topic", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Do(func(ctx context.Context, input *sqs.CreateQueueInput, opts ...*sns.Options) {
+				if assert.NotNil(t, input.QueueName) {
+					assert.True(t, strings.HasPrefix(*input.QueueName, "snstesting_"))
+					assert.True(t, strings.HasSuffix(*input.QueueName, ".fifo"))
+				}
+				assert.Equal(t, map[string]string{"FifoQueue": "true"}, input.Attributes)
+			}).
+			Return(&sqs.CreateQueueOutput{
+				QueueUrl: aws.String("http://queue.url"),
+			}, nil)
+
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{
+					"QueueArn": "arn:foo:bar:testingqueue.fifo",
+				},
+			}, nil)
+
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders.fifo"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue.fifo"),
+		}).Return(&sns.SubscribeOutput{
+			SubscriptionArn: aws.String("arn:foo:bar:subscription"),
+		}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123456789012:orders.fifo")
+		assert.NoError(t, err)
+		assert.True(t, subscriber.Config.FIFO)
+		assert.True(t, strings.HasSuffix(subscriber.Config.QueueName, ".fifo"))
+	})
+
 	t.Run("success, paging of topics list", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		SQS := mock.NewMockSQSAPI(ctrl)
@@ -564,10 +915,15 @@ func TestSubscriber_Receive(t *testing.T) {
 			WaitTimeSeconds:     3,
 		}).Return(&sqs.ReceiveMessageOutput{
 			Messages: []sqstypes.Message{
-				{Body: aws.String("this is the message!")},
+				{Body: aws.String("this is the message!"), ReceiptHandle: aws.String("receipt")},
 			},
 		}, nil)
 
+		SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String("http://queue.url"),
+			ReceiptHandle: aws.String("receipt"),
+		}).Return(&sqs.DeleteMessageOutput{}, nil)
+
 		subscriber := snstesting.Subscriber{
 			SNS: SNS,
 			SQS: SQS,
@@ -608,7 +964,7 @@ func TestSubscriber_Cleanup(t *testing.T) {
 		}
 
 		err := subscriber.Cleanup(ctx)
-		assert.EqualError(t, err, "foo,bar")
+		assert.EqualError(t, err, "leaked subscription arn:foo:bar:subscription: foo,leaked queue http://queue.url: bar")
 	})
 
 	t.Run("success", func(t *testing.T) {
@@ -623,6 +979,7 @@ func TestSubscriber_Cleanup(t *testing.T) {
 		SQS.EXPECT().DeleteQueue(ctx, &sqs.DeleteQueueInput{
 			QueueUrl: aws.String("http://queue.url"),
 		}).Return(&sqs.DeleteQueueOutput{}, nil)
+		expectGone(ctx, SNS, SQS, "http://queue.url", "arn:foo:bar:subscription")
 
 		subscriber := snstesting.Subscriber{
 			SNS: SNS,
@@ -637,3 +994,548 @@ func TestSubscriber_Cleanup(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestSubscriber_ReceiveMessage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String("http://queue.url"),
+			MaxNumberOfMessages: 1,
+			VisibilityTimeout:   3600,
+			WaitTimeSeconds:     3,
+		}).Return(&sqs.ReceiveMessageOutput{}, assert.AnError)
+
+		subscriber := snstesting.Subscriber{
+			SNS: SNS,
+			SQS: SQS,
+			Config: snstesting.Config{
+				QueueURL: "http://queue.url",
+			},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.Error(t, err)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("empty message", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String("http://queue.url"),
+			MaxNumberOfMessages: 1,
+			VisibilityTimeout:   3600,
+			WaitTimeSeconds:     3,
+		}).Return(&sqs.ReceiveMessageOutput{}, nil)
+
+		subscriber := snstesting.Subscriber{
+			SNS: SNS,
+			SQS: SQS,
+			Config: snstesting.Config{
+				QueueURL: "http://queue.url",
+			},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("not a notification", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String("http://queue.url"),
+			MaxNumberOfMessages: 1,
+			VisibilityTimeout:   3600,
+			WaitTimeSeconds:     3,
+		}).Return(&sqs.ReceiveMessageOutput{
+			Messages: []sqstypes.Message{
+				{Body: aws.String("this is the message!"), ReceiptHandle: aws.String("receipt")},
+			},
+		}, nil)
+
+		SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String("http://queue.url"),
+			ReceiptHandle: aws.String("receipt"),
+		}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+		subscriber := snstesting.Subscriber{
+			SNS: SNS,
+			SQS: SQS,
+			Config: snstesting.Config{
+				QueueURL: "http://queue.url",
+			},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.Error(t, err)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		body := `{
+			"Type": "Notification",
+			"MessageId": "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+			"TopicArn": "arn:foo:bar:sometopic",
+			"Subject": "some subject",
+			"Message": "this is the message!",
+			"Timestamp": "2023-03-21T10:11:12.123Z",
+			"SignatureVersion": "1",
+			"Signature": "c2lnbmF0dXJl",
+			"SigningCertURL": "https://sns.amazonaws.com/cert.pem",
+			"UnsubscribeURL": "https://sns.amazonaws.com/unsubscribe",
+			"MessageAttributes": {
+				"event": {"Type": "String", "Value": "created"},
+				"version": {"Type": "Number", "Value": "2"}
+			}
+		}`
+
+		SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String("http://queue.url"),
+			MaxNumberOfMessages: 1,
+			VisibilityTimeout:   3600,
+			WaitTimeSeconds:     3,
+		}).Return(&sqs.ReceiveMessageOutput{
+			Messages: []sqstypes.Message{
+				{Body: aws.String(body), ReceiptHandle: aws.String("receipt")},
+			},
+		}, nil)
+
+		SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String("http://queue.url"),
+			ReceiptHandle: aws.String("receipt"),
+		}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+		subscriber := snstesting.Subscriber{
+			SNS: SNS,
+			SQS: SQS,
+			Config: snstesting.Config{
+				QueueURL: "http://queue.url",
+			},
+		}
+
+		msg, err := subscriber.ReceiveMessage(ctx)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "Notification", msg.Type)
+			assert.Equal(t, "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324", msg.MessageID)
+			assert.Equal(t, "arn:foo:bar:sometopic", msg.TopicARN)
+			assert.Equal(t, "some subject", msg.Subject)
+			assert.Equal(t, "this is the message!", msg.Message)
+			assert.Equal(t, time.Date(2023, 3, 21, 10, 11, 12, 123000000, time.UTC), msg.Timestamp)
+			assert.Equal(t, "1", msg.SignatureVersion)
+			assert.Equal(t, "c2lnbmF0dXJl", msg.Signature)
+			assert.Equal(t, "https://sns.amazonaws.com/cert.pem", msg.SigningCertURL)
+			assert.Equal(t, "https://sns.amazonaws.com/unsubscribe", msg.UnsubscribeURL)
+			assert.Equal(t, "created", msg.MessageAttributes["event"].String())
+			version, err := msg.MessageAttributes["version"].Number()
+			assert.NoError(t, err)
+			assert.Equal(t, 2.0, version)
+			assert.Equal(t, body, msg.Body)
+			assert.Equal(t, "receipt", msg.ReceiptHandle)
+		}
+	})
+}
+
+func TestSubscriber_ReceiveMessage_RawMessageDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+	SNS := mock.NewMockSNSAPI(ctrl)
+
+	SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              aws.String("http://queue.url"),
+		MaxNumberOfMessages:   1,
+		VisibilityTimeout:     3600,
+		WaitTimeSeconds:       3,
+		MessageAttributeNames: []string{"All"},
+		AttributeNames:        []sqstypes.QueueAttributeName{"SentTimestamp"},
+	}).Return(&sqs.ReceiveMessageOutput{
+		Messages: []sqstypes.Message{
+			{
+				Body:          aws.String("this is the message!"),
+				ReceiptHandle: aws.String("receipt"),
+				Attributes: map[string]string{
+					"SentTimestamp": "1679393472123",
+				},
+				MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+					"event":   {DataType: aws.String("String"), StringValue: aws.String("created")},
+					"version": {DataType: aws.String("Number"), StringValue: aws.String("2")},
+					"blob":    {DataType: aws.String("Binary"), BinaryValue: []byte("foo")},
+				},
+			},
+		},
+	}, nil)
+
+	SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("http://queue.url"),
+		ReceiptHandle: aws.String("receipt"),
+	}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	subscriber := snstesting.Subscriber{
+		SNS: SNS,
+		SQS: SQS,
+		Config: snstesting.Config{
+			TopicARN:           "arn:foo:bar:sometopic",
+			QueueURL:           "http://queue.url",
+			RawMessageDelivery: true,
+		},
+	}
+
+	msg, err := subscriber.ReceiveMessage(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "Notification", msg.Type)
+		assert.Equal(t, "arn:foo:bar:sometopic", msg.TopicARN)
+		assert.Equal(t, "this is the message!", msg.Message)
+		assert.Equal(t, "this is the message!", msg.Body)
+		assert.Equal(t, "receipt", msg.ReceiptHandle)
+		assert.Equal(t, time.Date(2023, 3, 21, 10, 11, 12, 123000000, time.UTC), msg.Timestamp)
+		assert.Equal(t, map[string]snstesting.MessageAttribute{
+			"event":   {Type: "String", Value: "created"},
+			"version": {Type: "Number", Value: "2"},
+			"blob":    {Type: "Binary", Value: "Zm9v"},
+		}, msg.MessageAttributes)
+	}
+}
+
+func TestSubscriber_ReceiveMessage_FIFO(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+	SNS := mock.NewMockSNSAPI(ctrl)
+
+	SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String("http://queue.url"),
+		MaxNumberOfMessages: 1,
+		VisibilityTimeout:   3600,
+		WaitTimeSeconds:     3,
+		AttributeNames: []sqstypes.QueueAttributeName{
+			"MessageGroupId", "MessageDeduplicationId", "SequenceNumber",
+		},
+	}).Return(&sqs.ReceiveMessageOutput{
+		Messages: []sqstypes.Message{
+			{
+				Body:          aws.String(`{"Type": "Notification", "Message": "this is the message!", "SequenceNumber": "10000000000000003000"}`),
+				ReceiptHandle: aws.String("receipt"),
+				Attributes: map[string]string{
+					"MessageGroupId":         "group",
+					"MessageDeduplicationId": "dedup",
+					"SequenceNumber":         "18876543210000000000",
+				},
+			},
+		},
+	}, nil)
+
+	SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("http://queue.url"),
+		ReceiptHandle: aws.String("receipt"),
+	}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	subscriber := snstesting.Subscriber{
+		SNS: SNS,
+		SQS: SQS,
+		Config: snstesting.Config{
+			QueueURL: "http://queue.url",
+			FIFO:     true,
+		},
+	}
+
+	msg, err := subscriber.ReceiveMessage(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "this is the message!", msg.Message)
+		assert.Equal(t, "group", msg.MessageGroupID)
+		assert.Equal(t, "dedup", msg.MessageDeduplicationID)
+		assert.Equal(t, "10000000000000003000", msg.SequenceNumber, "sequence number given by SNS on publish")
+	}
+}
+
+func TestSubscriber_ReceiveMessage_FIFO_Raw(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+	SNS := mock.NewMockSNSAPI(ctrl)
+
+	SQS.EXPECT().ReceiveMessage(ctx, gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).Return(&sqs.ReceiveMessageOutput{
+		Messages: []sqstypes.Message{
+			{
+				Body:          aws.String("this is the message!"),
+				ReceiptHandle: aws.String("receipt"),
+				Attributes: map[string]string{
+					"MessageGroupId":         "group",
+					"MessageDeduplicationId": "dedup",
+					"SequenceNumber":         "18876543210000000000",
+				},
+			},
+		},
+	}, nil)
+
+	SQS.EXPECT().DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("http://queue.url"),
+		ReceiptHandle: aws.String("receipt"),
+	}).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	subscriber := snstesting.Subscriber{
+		SNS: SNS,
+		SQS: SQS,
+		Config: snstesting.Config{
+			QueueURL:           "http://queue.url",
+			FIFO:               true,
+			RawMessageDelivery: true,
+		},
+	}
+
+	msg, err := subscriber.ReceiveMessage(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "this is the message!", msg.Message)
+		assert.Equal(t, "group", msg.MessageGroupID)
+		assert.Equal(t, "dedup", msg.MessageDeduplicationID)
+		assert.Equal(t, "18876543210000000000", msg.SequenceNumber, "no SNS envelope, SQS sequence number")
+	}
+}
+
+func TestNewMultiSubscriber(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: nil}).
+			Return(&sns.ListTopicsOutput{
+				Topics: []snstypes.Topic{
+					{TopicArn: aws.String("arn:foo:bar:orders")},
+					{TopicArn: aws.String("arn:foo:bar:payments")},
+				},
+			}, nil).Times(3)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("http://queue.url")}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{"QueueArn": "arn:foo:bar:testingqueue"},
+			}, nil)
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Do(func(ctx context.Context, input *sqs.SetQueueAttributesInput, opts ...*sqs.Options) {
+				assert.Contains(t, input.Attributes["Policy"], `"aws:SourceArn": ["arn:foo:bar:orders","arn:foo:bar:payments"]`)
+			}).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		gomock.InOrder(
+			SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+				Protocol: aws.String("sqs"),
+				TopicArn: aws.String("arn:foo:bar:orders"),
+				Endpoint: aws.String("arn:foo:bar:testingqueue"),
+			}).Return(&sns.SubscribeOutput{SubscriptionArn: aws.String("arn:foo:bar:orders:sub")}, nil),
+			SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+				Protocol: aws.String("sqs"),
+				TopicArn: aws.String("arn:foo:bar:payments"),
+				Endpoint: aws.String("arn:foo:bar:testingqueue"),
+			}).Return(&sns.SubscribeOutput{SubscriptionArn: aws.String("arn:foo:bar:payments:sub")}, nil),
+		)
+
+		subscriber, err := snstesting.NewMultiSubscriber(ctx, SNS, SQS, []string{"orders", "payments", "orders"})
+		assert.NoError(t, err)
+		assert.Equal(t, "arn:foo:bar:orders", subscriber.Config.TopicARN)
+		assert.Equal(t, "arn:foo:bar:orders:sub", subscriber.Config.SubscriptionARN)
+		assert.Equal(t, []snstesting.Subscription{
+			{TopicName: "orders", TopicARN: "arn:foo:bar:orders", SubscriptionARN: "arn:foo:bar:orders:sub"},
+			{TopicName: "payments", TopicARN: "arn:foo:bar:payments", SubscriptionARN: "arn:foo:bar:payments:sub"},
+		}, subscriber.Config.Subscriptions)
+
+		SNS.EXPECT().Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: aws.String("arn:foo:bar:orders:sub")}).
+			Return(&sns.UnsubscribeOutput{}, nil)
+		SNS.EXPECT().Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: aws.String("arn:foo:bar:payments:sub")}).
+			Return(&sns.UnsubscribeOutput{}, nil)
+		SQS.EXPECT().DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: aws.String("http://queue.url")}).
+			Return(&sqs.DeleteQueueOutput{}, nil)
+		expectGone(ctx, SNS, SQS, "http://queue.url", "arn:foo:bar:orders:sub", "arn:foo:bar:payments:sub")
+
+		assert.NoError(t, subscriber.Cleanup(ctx))
+	})
+
+	t.Run("subscribe failure cleans up previous subscriptions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("http://queue.url")}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{"QueueArn": "arn:foo:bar:testingqueue"},
+			}, nil)
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+		gomock.InOrder(
+			SNS.EXPECT().Subscribe(ctx, gomock.AssignableToTypeOf(&sns.SubscribeInput{})).
+				Return(&sns.SubscribeOutput{SubscriptionArn: aws.String("arn:aws:sns:eu-west-1:123:orders:sub")}, nil),
+			SNS.EXPECT().Subscribe(ctx, gomock.AssignableToTypeOf(&sns.SubscribeInput{})).
+				Return(nil, errors.New("foo")),
+			SNS.EXPECT().Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: aws.String("arn:aws:sns:eu-west-1:123:orders:sub")}).
+				Return(&sns.UnsubscribeOutput{}, nil),
+			SQS.EXPECT().DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: aws.String("http://queue.url")}).
+				Return(&sqs.DeleteQueueOutput{}, nil),
+		)
+
+		subscriber, err := snstesting.NewMultiSubscriber(ctx, SNS, SQS, []string{
+			"arn:aws:sns:eu-west-1:123:orders",
+			"arn:aws:sns:eu-west-1:123:payments",
+		})
+		assert.EqualError(t, err, "subscribe failure: foo")
+		assert.Empty(t, subscriber)
+	})
+
+	t.Run("no topics", func(t *testing.T) {
+		_, err := snstesting.NewMultiSubscriber(ctx, nil, nil, nil)
+		assert.EqualError(t, err, "no topics given")
+	})
+
+	t.Run("raw message delivery", func(t *testing.T) {
+		_, err := snstesting.NewMultiSubscriber(ctx, nil, nil, []string{"orders", "payments"}, snstesting.WithRawMessageDelivery())
+		assert.EqualError(t, err, "raw message delivery can't be used with multiple topics, source topic would be unknown")
+	})
+
+	t.Run("fifo and standard topics", func(t *testing.T) {
+		_, err := snstesting.NewMultiSubscriber(ctx, nil, nil, []string{
+			"arn:aws:sns:eu-west-1:123:orders.fifo",
+			"arn:aws:sns:eu-west-1:123:payments",
+		})
+		assert.EqualError(t, err, "fifo and standard topics can't share a queue: arn:aws:sns:eu-west-1:123:orders.fifo, arn:aws:sns:eu-west-1:123:payments")
+	})
+}
+
+func TestNewSubscriber_QueueOptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().CreateQueue(ctx, gomock.AssignableToTypeOf(&sqs.CreateQueueInput{})).
+			Do(func(ctx context.Context, input *sqs.CreateQueueInput, opts ...*sqs.Options) {
+				if assert.NotNil(t, input.QueueName) {
+					assert.True(t, strings.HasPrefix(*input.QueueName, "orders-it-"))
+					assert.Len(t, *input.QueueName, len("orders-it-")+20)
+				}
+				assert.Equal(t, map[string]string{
+					"DelaySeconds":           "1",
+					"MessageRetentionPeriod": "600",
+					"KmsMasterKeyId":         "alias/testing",
+				}, input.Attributes)
+				assert.Equal(t, "orders", input.Tags["team"])
+				assert.Equal(t, "ci", input.Tags["env"])
+				assert.Equal(t, "jenkins", input.Tags[snstesting.TagOwner])
+				createdAt, err := time.Parse(time.RFC3339, input.Tags[snstesting.TagCreatedAt])
+				assert.NoError(t, err)
+				assert.WithinDuration(t, time.Now(), createdAt, time.Minute)
+			}).
+			Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("http://queue.url")}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.GetQueueAttributesInput{})).
+			Return(&sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{"QueueArn": "arn:foo:bar:testingqueue"},
+			}, nil)
+		SQS.EXPECT().SetQueueAttributes(ctx, gomock.AssignableToTypeOf(&sqs.SetQueueAttributesInput{})).
+			Return(&sqs.SetQueueAttributesOutput{}, nil)
+		SNS.EXPECT().Subscribe(ctx, &sns.SubscribeInput{
+			Protocol: aws.String("sqs"),
+			TopicArn: aws.String("arn:aws:sns:eu-west-1:123:orders"),
+			Endpoint: aws.String("arn:foo:bar:testingqueue"),
+			Attributes: map[string]string{
+				"DeliveryPolicy":     `{"healthyRetryPolicy": {"numRetries": 1}}`,
+				"RawMessageDelivery": "true",
+			},
+		}).Return(&sns.SubscribeOutput{SubscriptionArn: aws.String("arn:foo:bar:subscription")}, nil)
+
+		subscriber, err := snstesting.NewSubscriber(ctx, SNS, SQS, "arn:aws:sns:eu-west-1:123:orders",
+			snstesting.WithQueueNamePrefix("orders-it-"),
+			snstesting.WithMessageRetentionPeriod(10*time.Minute),
+			snstesting.WithKMSMasterKey("alias/testing"),
+			snstesting.WithQueueAttributes(map[string]string{"DelaySeconds": "1"}),
+			snstesting.WithQueueTags(map[string]string{"team": "orders"}),
+			snstesting.WithQueueTags(map[string]string{"env": "ci", snstesting.TagOwner: "overridden"}),
+			snstesting.WithOwner("jenkins"),
+			snstesting.WithSubscriptionAttributes(map[string]string{
+				"DeliveryPolicy":     `{"healthyRetryPolicy": {"numRetries": 1}}`,
+				"RawMessageDelivery": "false",
+			}),
+			snstesting.WithRawMessageDelivery(),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, "http://queue.url", subscriber.Config.QueueURL)
+	})
+
+	t.Run("long tag values", func(t *testing.T) {
+		backend := fake.New()
+		backend.CreateTopic("orders")
+
+		s, err := snstesting.NewSubscriber(ctx, backend.SNS(), backend.SQS(), "orders",
+			snstesting.WithOwner(strings.Repeat("ż", 300)))
+		assert.NoError(t, err)
+
+		tags, err := backend.SQS().ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: aws.String(s.Config.QueueURL)})
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("ż", 256), tags.Tags[snstesting.TagOwner], "truncated to 256 characters")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := snstesting.NewSubscriber(ctx, nil, nil, "orders",
+			snstesting.WithQueueNamePrefix("orders.it"),
+			snstesting.WithWaitTime(30*time.Second),
+			snstesting.WithVisibilityTimeout(time.Millisecond),
+			snstesting.WithMessageRetentionPeriod(time.Second),
+		)
+		assert.EqualError(t, err, "queue name prefix may contain alphanumeric characters, hyphens and underscores only: orders.it,"+
+			"wait time has to be between 1s and 20s: 30s,"+
+			"visibility timeout has to be between 1s and 12h: 1ms,"+
+			"message retention period has to be between 1m and 336h: 1s")
+	})
+}
+
+func TestSubscriber_Receive_PollingOptions(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+
+	SQS.EXPECT().ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String("http://queue.url"),
+		MaxNumberOfMessages: 1,
+		VisibilityTimeout:   30,
+		WaitTimeSeconds:     20,
+	}).Return(&sqs.ReceiveMessageOutput{}, nil)
+
+	subscriber := snstesting.Subscriber{
+		SQS: SQS,
+		Config: snstesting.Config{
+			QueueURL:          "http://queue.url",
+			WaitTime:          20 * time.Second,
+			VisibilityTimeout: 30 * time.Second,
+		},
+	}
+
+	msg, err := subscriber.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, msg)
+}
@@ -0,0 +1,163 @@
+package snstesting
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// streamBufferSize is capacity of Stream channels.
+	streamBufferSize = 10
+	// streamErrorBackoff is delay before polling again after a failed poll.
+	streamErrorBackoff = time.Second
+)
+
+// Stream delivers messages polled from ad-hoc queue by background goroutine, see Subscriber.Stream.
+type Stream struct {
+	msgs   chan *Message
+	errs   chan error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stream starts background poller delivering received messages on a buffered channel, so the test can react to
+// messages while driving the system under test. Poller stops when ctx is cancelled, on Stream.Stop or on Cleanup.
+// Messages received but not delivered by the time poller stops are put back to the local buffer, so they are not lost.
+func (s *Subscriber) Stream(ctx context.Context) *Stream {
+	ctx, cancel := context.WithCancel(ctx)
+	st := &Stream{
+		msgs:   make(chan *Message, streamBufferSize),
+		errs:   make(chan error, streamBufferSize),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	buf := s.buffer()
+	streams := s.activeStreams()
+	streams.add(st)
+
+	go func() {
+		defer func() {
+			streams.remove(st)
+			close(st.msgs)
+			close(st.errs)
+			close(st.done)
+		}()
+
+		for ctx.Err() == nil {
+			var msgs []*Message
+			if m := buf.pop(); m != nil {
+				msgs = []*Message{m}
+			} else {
+				var err error
+				msgs, err = s.receiveMessages(ctx, maxBatchSize, s.waitTimeSeconds())
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					st.report(err)
+					sleep(ctx, streamErrorBackoff)
+				}
+			}
+
+			for i, m := range msgs {
+				select {
+				case st.msgs <- m:
+				case <-ctx.Done():
+					buf.pushFront(msgs[i:]...)
+					return
+				}
+			}
+		}
+	}()
+
+	return st
+}
+
+// Messages returns channel messages are delivered on, it's closed once the stream stops.
+func (st *Stream) Messages() <-chan *Message {
+	return st.msgs
+}
+
+// Errors returns channel poll errors are reported on, it's closed once the stream stops.
+// Errors are dropped when nobody reads them and the channel is full, poller never blocks on reporting.
+func (st *Stream) Errors() <-chan error {
+	return st.errs
+}
+
+// Stop stops the poller and waits for it to finish. Messages already delivered to the channel can still be read.
+func (st *Stream) Stop() {
+	st.cancel()
+	<-st.done
+}
+
+// Done is closed once the stream stops.
+func (st *Stream) Done() <-chan struct{} {
+	return st.done
+}
+
+func (st *Stream) report(err error) {
+	select {
+	case st.errs <- err:
+	default:
+	}
+}
+
+func (s *Subscriber) activeStreams() *streamSet {
+	if s.streams == nil {
+		s.streams = &streamSet{}
+	}
+	return s.streams
+}
+
+// stopStreams stops all running streams, so they don't poll a queue that is going away.
+func (s *Subscriber) stopStreams() {
+	if s.streams == nil {
+		return
+	}
+	for _, st := range s.streams.all() {
+		st.Stop()
+	}
+}
+
+// streamSet keeps track of running streams of Subscriber.
+type streamSet struct {
+	mu      sync.Mutex
+	streams map[*Stream]struct{}
+}
+
+func (ss *streamSet) add(st *Stream) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.streams == nil {
+		ss.streams = make(map[*Stream]struct{})
+	}
+	ss.streams[st] = struct{}{}
+}
+
+func (ss *streamSet) remove(st *Stream) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.streams, st)
+}
+
+func (ss *streamSet) all() []*Stream {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	var v []*Stream
+	for st := range ss.streams {
+		v = append(v, st)
+	}
+	return v
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
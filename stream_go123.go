@@ -0,0 +1,17 @@
+//go:build go1.23
+
+package snstesting
+
+import "iter"
+
+// All returns iterator over streamed messages, iteration ends once the stream stops.
+// Breaking out of the loop doesn't stop the stream, use Stop for that.
+func (st *Stream) All() iter.Seq[Message] {
+	return func(yield func(Message) bool) {
+		for m := range st.msgs {
+			if !yield(*m) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build go1.23
+
+package snstesting_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_All(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	SQS := mock.NewMockSQSAPI(ctrl)
+
+	gomock.InOrder(
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{Messages: sqsMessages(0, 3)}, nil),
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			DoAndReturn(blockingReceive).AnyTimes(),
+	)
+	SQS.EXPECT().DeleteMessageBatch(gomock.Any(), gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+		Return(&sqs.DeleteMessageBatchOutput{}, nil)
+
+	subscriber := &snstesting.Subscriber{
+		SQS:    SQS,
+		Config: snstesting.Config{QueueURL: "http://queue.url"},
+	}
+
+	stream := subscriber.Stream(context.Background())
+	defer stream.Stop()
+
+	var got []string
+	for msg := range stream.All() {
+		got = append(got, msg.Message)
+		if msg.Message == "2" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"0", "1", "2"}, got)
+}
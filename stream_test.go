@@ -0,0 +1,174 @@
+package snstesting_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingReceive waits for context cancellation, just like long polling of an empty queue would.
+func blockingReceive(ctx context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSubscriber_Stream(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		gomock.InOrder(
+			SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(&sqs.ReceiveMessageOutput{Messages: sqsMessages(1, 3)}, nil),
+			SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				DoAndReturn(blockingReceive).AnyTimes(),
+		)
+		SQS.EXPECT().DeleteMessageBatch(gomock.Any(), gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+			Return(&sqs.DeleteMessageBatchOutput{}, nil)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+		subscriber.Release(&snstesting.Message{Message: "0"})
+
+		stream := subscriber.Stream(context.Background())
+		for i, want := range []string{"0", "1", "2"} {
+			select {
+			case msg := <-stream.Messages():
+				assert.Equal(t, want, msg.Message, "message %d", i)
+			case <-time.After(time.Second):
+				t.Fatalf("message %d not delivered", i)
+			}
+		}
+
+		stream.Stop()
+		_, ok := <-stream.Messages()
+		assert.False(t, ok)
+		_, ok = <-stream.Errors()
+		assert.False(t, ok)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		gomock.InOrder(
+			SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				Return(nil, assert.AnError),
+			SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+				DoAndReturn(blockingReceive).AnyTimes(),
+		)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		stream := subscriber.Stream(context.Background())
+		defer stream.Stop()
+
+		select {
+		case err := <-stream.Errors():
+			assert.ErrorIs(t, err, assert.AnError)
+		case <-time.After(time.Second):
+			t.Fatal("error not reported")
+		}
+	})
+
+	t.Run("stops on context cancel", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			DoAndReturn(blockingReceive).AnyTimes()
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := subscriber.Stream(ctx)
+		cancel()
+
+		select {
+		case <-stream.Done():
+		case <-time.After(time.Second):
+			t.Fatal("stream not stopped")
+		}
+	})
+
+	t.Run("undelivered messages are kept", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{Messages: sqsMessages(0, 10)}, nil)
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			Return(&sqs.ReceiveMessageOutput{Messages: sqsMessages(10, 20)}, nil)
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			DoAndReturn(blockingReceive).AnyTimes()
+		SQS.EXPECT().DeleteMessageBatch(gomock.Any(), gomock.AssignableToTypeOf(&sqs.DeleteMessageBatchInput{})).
+			Return(&sqs.DeleteMessageBatchOutput{}, nil).Times(2)
+
+		subscriber := &snstesting.Subscriber{
+			SQS:    SQS,
+			Config: snstesting.Config{QueueURL: "http://queue.url"},
+		}
+
+		stream := subscriber.Stream(context.Background())
+		// channel holds 10 messages, the rest waits in the poller
+		assert.Eventually(t, func() bool { return len(stream.Messages()) == 10 }, time.Second, 10*time.Millisecond)
+		stream.Stop()
+
+		var got []string
+		for msg := range stream.Messages() {
+			got = append(got, msg.Message)
+		}
+		msgs, err := subscriber.ReceiveBatch(context.Background(), 10)
+		assert.NoError(t, err)
+		for _, msg := range msgs {
+			got = append(got, msg.Message)
+		}
+		assert.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19"}, got)
+	})
+
+	t.Run("stopped by cleanup", func(t *testing.T) {
+		ctx := context.Background()
+		ctrl := gomock.NewController(t)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		SQS := mock.NewMockSQSAPI(ctrl)
+
+		SQS.EXPECT().ReceiveMessage(gomock.Any(), gomock.AssignableToTypeOf(&sqs.ReceiveMessageInput{})).
+			DoAndReturn(blockingReceive).AnyTimes()
+		SNS.EXPECT().Unsubscribe(ctx, gomock.AssignableToTypeOf(&sns.UnsubscribeInput{})).Return(&sns.UnsubscribeOutput{}, nil)
+		SQS.EXPECT().DeleteQueue(ctx, gomock.AssignableToTypeOf(&sqs.DeleteQueueInput{})).Return(&sqs.DeleteQueueOutput{}, nil)
+		expectGone(ctx, SNS, SQS, "http://queue.url", "subscription:arn")
+
+		subscriber := &snstesting.Subscriber{
+			SNS: SNS,
+			SQS: SQS,
+			Config: snstesting.Config{
+				QueueURL:        "http://queue.url",
+				SubscriptionARN: "subscription:arn",
+			},
+		}
+
+		stream := subscriber.Stream(ctx)
+		assert.NoError(t, subscriber.Cleanup(ctx))
+
+		select {
+		case <-stream.Done():
+		default:
+			t.Fatal("stream not stopped")
+		}
+	})
+}
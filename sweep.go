@@ -0,0 +1,271 @@
+package snstesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Tags set on every ad-hoc queue, so leftovers of killed test runs can be found and removed, see Sweep.
+const (
+	TagCreatedAt = "snstesting:created-at"
+	TagOwner     = "snstesting:owner"
+	TagTestName  = "snstesting:test"
+)
+
+// Leftover is ad-hoc queue, together with its subscriptions, which outlived the test that created it.
+type Leftover struct {
+	QueueURL      string
+	QueueARN      string
+	CreatedAt     time.Time
+	Owner         string
+	TestName      string
+	Subscriptions []Subscription
+}
+
+// Age of the leftover at given time.
+func (l Leftover) Age(now time.Time) time.Duration {
+	return now.Sub(l.CreatedAt)
+}
+
+// Sweep removes ad-hoc queues created more than olderThan ago, together with their subscriptions.
+// It's meant for cleaning up after test runs that were killed before t.Cleanup had a chance to run.
+// Queues are looked up by default prefix, see Sweeper for more control and dry runs.
+func Sweep(ctx context.Context, SNS SNSAPI, SQS SQSAPI, olderThan time.Duration) ([]Leftover, error) {
+	return Sweeper{SNS: SNS, SQS: SQS}.Sweep(ctx, olderThan)
+}
+
+// Sweeper finds and removes leftovers of ad-hoc queues and subscriptions.
+type Sweeper struct {
+	SNS SNSAPI
+	SQS SQSAPI
+	// QueueNamePrefix of ad-hoc queues, 'snstesting_' by default. See WithQueueNamePrefix.
+	QueueNamePrefix string
+	// DryRun makes Sweep report what would be removed, without removing anything.
+	DryRun bool
+	// IncludeUntagged makes queues matching the prefix, but missing snstesting tags, sweepable by their SQS creation
+	// time, e.g. ad-hoc queues of older versions. Beware, queues not created by snstesting get removed as well.
+	IncludeUntagged bool
+	// Skipped is called with URL of every queue matching the prefix, but skipped for missing snstesting tags.
+	Skipped func(queueURL string)
+	// Now gives current time, time.Now by default.
+	Now func() time.Time
+}
+
+// Sweep removes leftovers created more than olderThan ago and returns them, in dry run mode it only reports them.
+// Removal keeps going when some leftover can't be removed, all errors are returned combined.
+func (sw Sweeper) Sweep(ctx context.Context, olderThan time.Duration) ([]Leftover, error) {
+	leftovers, err := sw.Leftovers(ctx, olderThan)
+	if err != nil || sw.DryRun {
+		return leftovers, err
+	}
+
+	var swept []Leftover
+	var errs []error
+	for _, l := range leftovers {
+		var lerrs []error
+		for _, sub := range l.Subscriptions {
+			lerrs = append(lerrs, unsubscribe(ctx, sw.SNS, sub.SubscriptionARN))
+		}
+		lerrs = append(lerrs, cleanupQueue(ctx, sw.SQS, l.QueueURL))
+		if err := combineErr(lerrs...); err != nil {
+			errs = append(errs, fmt.Errorf("sweep %s failure: %v", l.QueueURL, err))
+			continue
+		}
+		swept = append(swept, l)
+	}
+	return swept, combineErr(errs...)
+}
+
+// SweepLedger removes resources recorded in the leak ledger by Cleanup, regardless of their age, see WithLeakLedger.
+// Removed resources, and ones already gone, are dropped from the ledger and returned, others are kept for the next run.
+// In dry run mode it only reports what would be removed. Tests must not append to the ledger while it's swept.
+func (sw Sweeper) SweepLedger(ctx context.Context, path string) ([]Leak, error) {
+	leaks, err := ReadLedger(path)
+	if err != nil || sw.DryRun {
+		return leaks, err
+	}
+
+	var swept, kept []Leak
+	var errs []error
+	for _, l := range leaks {
+		var err error
+		if l.SubscriptionARN != "" {
+			err = removeSubscription(ctx, sw.SNS, l.SubscriptionARN)
+		} else {
+			err = removeQueue(ctx, sw.SQS, l.QueueURL)
+		}
+		if err != nil && !unverified(err) {
+			errs = append(errs, fmt.Errorf("sweep %s failure: %v", l.resource(), err))
+			kept = append(kept, l)
+			continue
+		}
+		swept = append(swept, l)
+	}
+	if err := writeLedger(path, kept); err != nil {
+		errs = append(errs, fmt.Errorf("write leak ledger failure: %v", err))
+	}
+	return swept, combineErr(errs...)
+}
+
+// Leftovers lists ad-hoc queues created more than olderThan ago, together with their subscriptions.
+// With zero olderThan all ad-hoc queues are listed, including ones used by tests running right now.
+func (sw Sweeper) Leftovers(ctx context.Context, olderThan time.Duration) ([]Leftover, error) {
+	now := time.Now()
+	if sw.Now != nil {
+		now = sw.Now()
+	}
+
+	queueURLs, err := sw.listQueues(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list queues failure: %v", err)
+	}
+
+	var leftovers []Leftover
+	for _, queueURL := range queueURLs {
+		l, err := sw.describeQueue(ctx, queueURL)
+		if err != nil {
+			if errors.Is(err, errQueueGone) {
+				// removed meanwhile, e.g. by t.Cleanup
+				continue
+			}
+			if errors.Is(err, errUntagged) {
+				if sw.Skipped != nil {
+					sw.Skipped(queueURL)
+				}
+				continue
+			}
+			return nil, err
+		}
+		if l.Age(now) >= olderThan {
+			leftovers = append(leftovers, l)
+		}
+	}
+	if len(leftovers) == 0 {
+		return nil, nil
+	}
+
+	subscriptions, err := sw.listSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list subscriptions failure: %v", err)
+	}
+	for i := range leftovers {
+		leftovers[i].Subscriptions = subscriptions[leftovers[i].QueueARN]
+	}
+	return leftovers, nil
+}
+
+func (sw Sweeper) listQueues(ctx context.Context) ([]string, error) {
+	prefix := sw.QueueNamePrefix
+	if prefix == "" {
+		prefix = defaultQueueNamePrefix
+	}
+
+	var queueURLs []string
+	var nextToken *string
+	for {
+		out, err := sw.SQS.ListQueues(ctx, &sqs.ListQueuesInput{
+			QueueNamePrefix: aws.String(prefix),
+			MaxResults:      aws.Int32(1000),
+			NextToken:       nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		queueURLs = append(queueURLs, out.QueueUrls...)
+		if out.NextToken == nil {
+			return queueURLs, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// describeQueue reads queue ARN, creation time and snstesting tags, queues without the tags give errUntagged.
+// With IncludeUntagged creation time of such queues is taken from queue attributes instead.
+func (sw Sweeper) describeQueue(ctx context.Context, queueURL string) (Leftover, error) {
+	attrs, err := sw.SQS.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{
+			types.QueueAttributeNameQueueArn,
+			types.QueueAttributeNameCreatedTimestamp,
+		},
+	})
+	if err != nil {
+		return Leftover{}, queueError("get queue attributes", err)
+	}
+	tags, err := sw.SQS.ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: aws.String(queueURL)})
+	if err != nil {
+		return Leftover{}, queueError("list queue tags", err)
+	}
+
+	l := Leftover{
+		QueueURL: queueURL,
+		QueueARN: attrs.Attributes[string(types.QueueAttributeNameQueueArn)],
+		Owner:    tags.Tags[TagOwner],
+		TestName: tags.Tags[TagTestName],
+	}
+	if createdAt, ok := tags.Tags[TagCreatedAt]; ok && l.Owner != "" {
+		l.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+		if err != nil {
+			return Leftover{}, fmt.Errorf("parse %s tag of %s failure: %v", TagCreatedAt, queueURL, err)
+		}
+		return l, nil
+	}
+	if !sw.IncludeUntagged {
+		return Leftover{}, errUntagged
+	}
+
+	seconds, err := strconv.ParseInt(attrs.Attributes[string(types.QueueAttributeNameCreatedTimestamp)], 10, 64)
+	if err != nil {
+		return Leftover{}, fmt.Errorf("parse created timestamp of %s failure: %v", queueURL, err)
+	}
+	l.CreatedAt = time.Unix(seconds, 0).UTC()
+	return l, nil
+}
+
+// listSubscriptions gives SQS subscriptions of all topics, by queue ARN.
+func (sw Sweeper) listSubscriptions(ctx context.Context) (map[string][]Subscription, error) {
+	subscriptions := map[string][]Subscription{}
+	var nextToken *string
+	for {
+		out, err := sw.SNS.ListSubscriptions(ctx, &sns.ListSubscriptionsInput{NextToken: nextToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range out.Subscriptions {
+			if aws.ToString(sub.Protocol) != "sqs" {
+				continue
+			}
+			queueArn := aws.ToString(sub.Endpoint)
+			subscriptions[queueArn] = append(subscriptions[queueArn], Subscription{
+				TopicName:       topicNameFromArn(aws.ToString(sub.TopicArn)),
+				TopicARN:        aws.ToString(sub.TopicArn),
+				SubscriptionARN: aws.ToString(sub.SubscriptionArn),
+			})
+		}
+		if out.NextToken == nil {
+			return subscriptions, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// errUntagged tells that queue matching the prefix was not tagged by snstesting, so it's not known to be ad-hoc queue.
+var errUntagged = errors.New("queue has no snstesting tags")
+
+// errQueueGone tells that queue was removed between listing and describing it.
+var errQueueGone = errors.New("queue does not exist")
+
+func queueError(op string, err error) error {
+	if isQueueGone(err) {
+		return errQueueGone
+	}
+	return fmt.Errorf("%s failure: %v", op, err)
+}
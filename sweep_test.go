@@ -0,0 +1,217 @@
+package snstesting_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweeper(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// expectQueues sets up two queues: 'old' created 2 hours ago and tagged, 'fresh' created a minute ago and untagged.
+	expectQueues := func(SQS *mock.MockSQSAPI) {
+		gomock.InOrder(
+			SQS.EXPECT().ListQueues(ctx, &sqs.ListQueuesInput{
+				QueueNamePrefix: aws.String("snstesting_"),
+				MaxResults:      aws.Int32(1000),
+			}).Return(&sqs.ListQueuesOutput{
+				QueueUrls: []string{"http://queue.url/snstesting_old"},
+				NextToken: aws.String("next"),
+			}, nil),
+			SQS.EXPECT().ListQueues(ctx, &sqs.ListQueuesInput{
+				QueueNamePrefix: aws.String("snstesting_"),
+				MaxResults:      aws.Int32(1000),
+				NextToken:       aws.String("next"),
+			}).Return(&sqs.ListQueuesOutput{
+				QueueUrls: []string{"http://queue.url/snstesting_fresh", "http://queue.url/snstesting_gone"},
+			}, nil),
+		)
+
+		queue := func(name string, attrs, tags map[string]string) {
+			SQS.EXPECT().GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+				QueueUrl: aws.String("http://queue.url/" + name),
+				AttributeNames: []sqstypes.QueueAttributeName{
+					sqstypes.QueueAttributeNameQueueArn,
+					sqstypes.QueueAttributeNameCreatedTimestamp,
+				},
+			}).Return(&sqs.GetQueueAttributesOutput{Attributes: attrs}, nil)
+			SQS.EXPECT().ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: aws.String("http://queue.url/" + name)}).
+				Return(&sqs.ListQueueTagsOutput{Tags: tags}, nil)
+		}
+		queue("snstesting_old", map[string]string{"QueueArn": "arn:aws:sqs:eu-west-1:123:snstesting_old"}, map[string]string{
+			snstesting.TagCreatedAt: "2023-03-10T10:00:00Z",
+			snstesting.TagOwner:     "jenkins",
+			snstesting.TagTestName:  "TestOrders",
+		})
+		queue("snstesting_fresh", map[string]string{
+			"QueueArn":         "arn:aws:sqs:eu-west-1:123:snstesting_fresh",
+			"CreatedTimestamp": "1678449540", // 2023-03-10T11:59:00Z
+		}, nil)
+		SQS.EXPECT().GetQueueAttributes(ctx, gomock.Eq(&sqs.GetQueueAttributesInput{
+			QueueUrl: aws.String("http://queue.url/snstesting_gone"),
+			AttributeNames: []sqstypes.QueueAttributeName{
+				sqstypes.QueueAttributeNameQueueArn,
+				sqstypes.QueueAttributeNameCreatedTimestamp,
+			},
+		})).Return(nil, &smithy.GenericAPIError{Code: "AWS.SimpleQueueService.NonExistentQueue"})
+	}
+
+	expectSubscriptions := func(SNS *mock.MockSNSAPI) {
+		SNS.EXPECT().ListSubscriptions(ctx, &sns.ListSubscriptionsInput{}).
+			Return(&sns.ListSubscriptionsOutput{
+				Subscriptions: []snstypes.Subscription{
+					{
+						Protocol:        aws.String("sqs"),
+						Endpoint:        aws.String("arn:aws:sqs:eu-west-1:123:snstesting_old"),
+						TopicArn:        aws.String("arn:aws:sns:eu-west-1:123:orders"),
+						SubscriptionArn: aws.String("arn:aws:sns:eu-west-1:123:orders:1"),
+					},
+					{
+						Protocol:        aws.String("lambda"),
+						Endpoint:        aws.String("arn:aws:sqs:eu-west-1:123:snstesting_old"),
+						TopicArn:        aws.String("arn:aws:sns:eu-west-1:123:orders"),
+						SubscriptionArn: aws.String("arn:aws:sns:eu-west-1:123:orders:2"),
+					},
+				},
+			}, nil)
+	}
+
+	oldLeftover := snstesting.Leftover{
+		QueueURL:  "http://queue.url/snstesting_old",
+		QueueARN:  "arn:aws:sqs:eu-west-1:123:snstesting_old",
+		CreatedAt: time.Date(2023, 3, 10, 10, 0, 0, 0, time.UTC),
+		Owner:     "jenkins",
+		TestName:  "TestOrders",
+		Subscriptions: []snstesting.Subscription{{
+			TopicName:       "orders",
+			TopicARN:        "arn:aws:sns:eu-west-1:123:orders",
+			SubscriptionARN: "arn:aws:sns:eu-west-1:123:orders:1",
+		}},
+	}
+
+	t.Run("leftovers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		expectQueues(SQS)
+		expectSubscriptions(SNS)
+
+		var skipped []string
+		sweeper := snstesting.Sweeper{SNS: SNS, SQS: SQS, Now: func() time.Time { return now }}
+		sweeper.Skipped = func(queueURL string) { skipped = append(skipped, queueURL) }
+		leftovers, err := sweeper.Leftovers(ctx, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Leftover{oldLeftover}, leftovers, "untagged queue is not known to be ad-hoc queue")
+		assert.Equal(t, []string{"http://queue.url/snstesting_fresh"}, skipped)
+	})
+
+	t.Run("include untagged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		expectQueues(SQS)
+		expectSubscriptions(SNS)
+
+		sweeper := snstesting.Sweeper{SNS: SNS, SQS: SQS, IncludeUntagged: true, Now: func() time.Time { return now }}
+		leftovers, err := sweeper.Leftovers(ctx, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Leftover{
+			oldLeftover,
+			{
+				QueueURL:  "http://queue.url/snstesting_fresh",
+				QueueARN:  "arn:aws:sqs:eu-west-1:123:snstesting_fresh",
+				CreatedAt: time.Date(2023, 3, 10, 11, 59, 0, 0, time.UTC),
+			},
+		}, leftovers)
+		assert.Equal(t, time.Minute, leftovers[1].Age(now))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		expectQueues(SQS)
+		expectSubscriptions(SNS)
+
+		sweeper := snstesting.Sweeper{SNS: SNS, SQS: SQS, DryRun: true, Now: func() time.Time { return now }}
+		leftovers, err := sweeper.Sweep(ctx, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Leftover{oldLeftover}, leftovers)
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		expectQueues(SQS)
+		expectSubscriptions(SNS)
+
+		gomock.InOrder(
+			SNS.EXPECT().Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: aws.String("arn:aws:sns:eu-west-1:123:orders:1")}).
+				Return(&sns.UnsubscribeOutput{}, nil),
+			SQS.EXPECT().DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: aws.String("http://queue.url/snstesting_old")}).
+				Return(&sqs.DeleteQueueOutput{}, nil),
+		)
+
+		sweeper := snstesting.Sweeper{SNS: SNS, SQS: SQS, Now: func() time.Time { return now }}
+		leftovers, err := sweeper.Sweep(ctx, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []snstesting.Leftover{oldLeftover}, leftovers)
+	})
+
+	t.Run("sweep failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+		expectQueues(SQS)
+		expectSubscriptions(SNS)
+
+		SNS.EXPECT().Unsubscribe(ctx, gomock.AssignableToTypeOf(&sns.UnsubscribeInput{})).Return(nil, errors.New("foo"))
+		SQS.EXPECT().DeleteQueue(ctx, gomock.AssignableToTypeOf(&sqs.DeleteQueueInput{})).Return(&sqs.DeleteQueueOutput{}, nil)
+
+		sweeper := snstesting.Sweeper{SNS: SNS, SQS: SQS, Now: func() time.Time { return now }}
+		leftovers, err := sweeper.Sweep(ctx, time.Hour)
+		assert.EqualError(t, err, "sweep http://queue.url/snstesting_old failure: foo")
+		assert.Empty(t, leftovers)
+	})
+
+	t.Run("nothing to sweep", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().ListQueues(ctx, &sqs.ListQueuesInput{
+			QueueNamePrefix: aws.String("orders-it-"),
+			MaxResults:      aws.Int32(1000),
+		}).Return(&sqs.ListQueuesOutput{}, nil)
+
+		leftovers, err := snstesting.Sweeper{SNS: SNS, SQS: SQS, QueueNamePrefix: "orders-it-"}.Sweep(ctx, time.Hour)
+		assert.NoError(t, err)
+		assert.Empty(t, leftovers)
+	})
+
+	t.Run("list queues failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		SQS := mock.NewMockSQSAPI(ctrl)
+		SNS := mock.NewMockSNSAPI(ctrl)
+
+		SQS.EXPECT().ListQueues(ctx, gomock.AssignableToTypeOf(&sqs.ListQueuesInput{})).Return(nil, errors.New("foo"))
+
+		_, err := snstesting.Sweep(ctx, SNS, SQS, time.Hour)
+		assert.EqualError(t, err, "list queues failure: foo")
+	})
+}
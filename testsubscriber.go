@@ -0,0 +1,215 @@
+package snstesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// TB is the part of testing.TB test helpers depend on. Besides *testing.T it's satisfied by *testing.B, *testing.F,
+// testing.TB itself and test doubles of other frameworks, like Ginkgo's GinkgoT().
+// When TB also has Context method (testing.TB does since Go 1.24), its context is used for calls made by helpers.
+type TB interface {
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	Fatal(args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Helper()
+	Name() string
+}
+
+// testContext gives context of the test, when TB has one, or background context otherwise.
+func testContext(t TB) context.Context {
+	if c, ok := t.(interface{ Context() context.Context }); ok {
+		return c.Context()
+	}
+	return context.Background()
+}
+
+// TestSubscriber is Subscriber bound to the test, in case of an error t.Fatal is executed.
+type TestSubscriber struct {
+	Subscriber *Subscriber
+
+	t   TB
+	ctx context.Context
+}
+
+// NewTestSubscriber creates Subscriber for testing purposes based on provided AWS configuration.
+// Ad-hoc resources are cleaned up after the test automatically with use of t.Cleanup.
+// In case of an error, t.Fatal is executed.
+func NewTestSubscriber(t TB, cfg aws.Config, topicName string, opts ...Option) *TestSubscriber {
+	t.Helper()
+	return newTestSubscriber(t, func(ctx context.Context, SNS SNSAPI, SQS SQSAPI, defaults Option) (Subscriber, error) {
+		return NewSubscriber(ctx, SNS, SQS, topicName, append([]Option{defaults}, opts...)...)
+	}, cfg)
+}
+
+// NewMultiTestSubscriber works like NewTestSubscriber, but watches all given topics through single queue.
+// See NewMultiSubscriber.
+func NewMultiTestSubscriber(t TB, cfg aws.Config, topicNames []string, opts ...Option) *TestSubscriber {
+	t.Helper()
+	return newTestSubscriber(t, func(ctx context.Context, SNS SNSAPI, SQS SQSAPI, defaults Option) (Subscriber, error) {
+		return NewMultiSubscriber(ctx, SNS, SQS, topicNames, append([]Option{defaults}, opts...)...)
+	}, cfg)
+}
+
+func newTestSubscriber(t TB, create func(context.Context, SNSAPI, SQSAPI, Option) (Subscriber, error), cfg aws.Config) *TestSubscriber {
+	t.Helper()
+
+	ctx := testContext(t)
+
+	SNS := sns.NewFromConfig(cfg)
+	SQS := sqs.NewFromConfig(cfg)
+
+	s, err := create(ctx, SNS, SQS, withTestDefaults(t.Name(), cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		// context of the test is already cancelled when cleanup runs
+		ctx, cancel := context.WithTimeout(context.Background(), s.cleanupTimeout())
+		defer cancel()
+		notes, err := s.cleanup(ctx)
+		for _, note := range notes {
+			logf(t, "snstesting: %s", note)
+		}
+		reportLeaks(t, err)
+	})
+
+	return &TestSubscriber{
+		Subscriber: &s,
+		t:          t,
+		ctx:        ctx,
+	}
+}
+
+// Receive receives single raw message, see Subscriber.Receive.
+func (ts *TestSubscriber) Receive() string {
+	ts.t.Helper()
+
+	msg, err := ts.Subscriber.Receive(ts.ctx)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return msg
+}
+
+// ReceiveMessage receives single parsed message, see Subscriber.ReceiveMessage.
+func (ts *TestSubscriber) ReceiveMessage() *Message {
+	ts.t.Helper()
+
+	msg, err := ts.Subscriber.ReceiveMessage(ts.ctx)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return msg
+}
+
+// ReceiveMatching waits for message matching the predicate, see Subscriber.ReceiveMatching.
+func (ts *TestSubscriber) ReceiveMatching(match Predicate, timeout time.Duration) *Message {
+	ts.t.Helper()
+
+	msg, err := ts.Subscriber.ReceiveMatching(ts.ctx, match, timeout)
+	if errors.Is(err, ErrTimeout) {
+		ts.t.Fatalf("%v\n%s", err, ts.diagnose())
+	}
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return msg
+}
+
+// ExpectNoMessage fails the test if message matching any of predicates arrives within the time window,
+// see Subscriber.ExpectNoMessage.
+func (ts *TestSubscriber) ExpectNoMessage(within time.Duration, match ...Predicate) {
+	ts.t.Helper()
+
+	err := ts.Subscriber.ExpectNoMessage(ts.ctx, within, match...)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+}
+
+// ReceiveBatch receives up to max messages, see Subscriber.ReceiveBatch.
+func (ts *TestSubscriber) ReceiveBatch(max int) []*Message {
+	ts.t.Helper()
+
+	msgs, err := ts.Subscriber.ReceiveBatch(ts.ctx, max)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return msgs
+}
+
+// Drain receives everything until no new messages arrive for the quiet period, see Subscriber.Drain.
+func (ts *TestSubscriber) Drain(quietPeriod time.Duration) []*Message {
+	ts.t.Helper()
+
+	msgs, err := ts.Subscriber.Drain(ts.ctx, quietPeriod)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return msgs
+}
+
+// NewTestSubscriberFrom binds already existing Subscriber to the test, in case of an error t.Fatal is executed.
+// Unlike NewTestSubscriber, it does not register any cleanup, this is up to the caller.
+func NewTestSubscriberFrom(t TB, s *Subscriber) *TestSubscriber {
+	return &TestSubscriber{
+		Subscriber: s,
+		t:          t,
+		ctx:        testContext(t),
+	}
+}
+
+// Decode decodes message payload into v, see Subscriber.Decode.
+func (ts *TestSubscriber) Decode(msg *Message, v interface{}) {
+	ts.t.Helper()
+
+	if err := ts.Subscriber.Decode(msg, v); err != nil {
+		ts.t.Fatalf("%v, payload: %s", err, msg.Message)
+	}
+}
+
+// Stream starts background poller, see Subscriber.Stream. It's stopped at the end of the test.
+func (ts *TestSubscriber) Stream() *Stream {
+	st := ts.Subscriber.Stream(ts.ctx)
+	ts.t.Cleanup(st.Stop)
+	return st
+}
+
+// diagnose gives diagnosis of the subscriber, for failure of wait timing out.
+func (ts *TestSubscriber) diagnose() string {
+	d, err := ts.Subscriber.Diagnose(ts.ctx)
+	if err != nil {
+		return fmt.Sprintf("%s\n(diagnosis incomplete: %v)", d, err)
+	}
+	return d.String()
+}
+
+// withTestDefaults sets name of the test, and KMS client checking KMS key of ad-hoc queue when it's used.
+// Options given by the test come after it.
+func withTestDefaults(name string, cfg aws.Config) Option {
+	return func(c *Config) {
+		c.TestName = name
+		c.newKMS = func() KMSAPI {
+			return kms.NewFromConfig(cfg)
+		}
+	}
+}
+
+// logf logs with t.Logf, provided TB can log, like testing.TB does.
+func logf(t TB, format string, args ...interface{}) {
+	if l, ok := t.(interface {
+		Logf(format string, args ...interface{})
+	}); ok {
+		l.Logf(format, args...)
+	}
+}
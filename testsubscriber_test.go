@@ -0,0 +1,328 @@
+package snstesting_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/emulator"
+	"github.com/prozz/snstesting/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTB records what helpers do with the test, Fatal stops the calling goroutine the same way testing does.
+type fakeTB struct {
+	name string
+
+	mu       sync.Mutex
+	helper   bool
+	errors   []string
+	fatal    string
+	cleanups []func()
+}
+
+var _ snstesting.TB = (*fakeTB)(nil)
+
+func (tb *fakeTB) Cleanup(f func()) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Fatal(args ...interface{}) {
+	tb.mu.Lock()
+	tb.fatal = fmt.Sprint(args...)
+	tb.mu.Unlock()
+	runtime.Goexit()
+}
+
+func (tb *fakeTB) Fatalf(format string, args ...interface{}) {
+	tb.Fatal(fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Helper() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.helper = true
+}
+
+func (tb *fakeTB) Name() string {
+	return tb.name
+}
+
+// run calls f the way test framework calls test functions, tells if f finished without calling Fatal.
+func (tb *fakeTB) run(f func()) bool {
+	done := make(chan bool)
+	go func() {
+		finished := false
+		defer func() { done <- finished }()
+		f()
+		finished = true
+	}()
+	return <-done
+}
+
+// finish runs registered cleanups in reverse order, the way testing does.
+func (tb *fakeTB) finish() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.run(tb.cleanups[i])
+	}
+	tb.cleanups = nil
+}
+
+// contextTB is fakeTB with context of the test, like testing.TB since Go 1.24.
+type contextTB struct {
+	*fakeTB
+	ctx context.Context
+}
+
+func (tb contextTB) Context() context.Context {
+	return tb.ctx
+}
+
+// setupEmulator runs emulator with given topics created.
+func setupEmulator(t *testing.T, topics ...string) (*emulator.Server, map[string]string) {
+	s := emulator.NewServer(fake.New())
+	t.Cleanup(s.Close)
+
+	arns := map[string]string{}
+	for _, topic := range topics {
+		arns[topic] = s.Backend.CreateTopic(topic)
+	}
+	return s, arns
+}
+
+func publishTo(t *testing.T, s *emulator.Server, topicArn, message string) {
+	t.Helper()
+
+	_, err := s.Backend.SNS().Publish(context.Background(), &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Message:  aws.String(message),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{
+			"length": {DataType: aws.String("Number"), StringValue: aws.String(fmt.Sprint(len(message)))},
+		},
+	})
+	assert.NoError(t, err)
+}
+
+func TestTB(t *testing.T) {
+	fastPolling := snstesting.WithWaitTime(time.Second)
+
+	t.Run("New", func(t *testing.T) {
+		s, topics := setupEmulator(t, "orders")
+		tb := &fakeTB{name: "TestOrders/created"}
+
+		var receive snstesting.ReceiveFn
+		assert.True(t, tb.run(func() { receive = snstesting.New(tb, s.Config(), "orders", fastPolling) }))
+		assert.True(t, tb.helper)
+		assert.Len(t, tb.cleanups, 1)
+
+		urls := s.Backend.QueueURLs()
+		if assert.Len(t, urls, 1) {
+			tags, err := s.Backend.SQS().ListQueueTags(context.Background(), &sqs.ListQueueTagsInput{QueueUrl: aws.String(urls[0])})
+			assert.NoError(t, err)
+			assert.Equal(t, "TestOrders/created", tags.Tags[snstesting.TagTestName])
+		}
+
+		publishTo(t, s, topics["orders"], "hello")
+		var got string
+		assert.True(t, tb.run(func() { got = receive() }))
+		assert.Contains(t, got, `"Message":"hello"`)
+
+		assert.True(t, tb.run(func() { got = receive() }))
+		assert.Empty(t, got)
+
+		tb.finish()
+		assert.Empty(t, tb.fatal)
+		assert.Empty(t, s.Backend.QueueURLs(), "cleaned up")
+	})
+
+	t.Run("New fails", func(t *testing.T) {
+		s, _ := setupEmulator(t)
+		tb := &fakeTB{name: "TestOrders"}
+
+		assert.False(t, tb.run(func() { snstesting.New(tb, s.Config(), "orders") }))
+		assert.Equal(t, "topic orders not found", tb.fatal)
+		assert.Empty(t, tb.cleanups)
+	})
+
+	t.Run("NewMessageReceiver and ReceiveJSON", func(t *testing.T) {
+		s, topics := setupEmulator(t, "orders")
+		tb := &fakeTB{name: "TestOrders"}
+		defer tb.finish()
+
+		type order struct {
+			ID string `json:"id"`
+		}
+
+		var receive snstesting.ReceiveMessageFn
+		assert.True(t, tb.run(func() { receive = snstesting.NewMessageReceiver(tb, s.Config(), "orders", fastPolling) }))
+
+		publishTo(t, s, topics["orders"], `{"id": "1"}`)
+		var got *order
+		assert.True(t, tb.run(func() { got = snstesting.ReceiveJSON[order](tb, receive) }))
+		assert.Equal(t, &order{ID: "1"}, got)
+
+		publishTo(t, s, topics["orders"], `{"id": 1}`)
+		assert.False(t, tb.run(func() { snstesting.ReceiveJSON[order](tb, receive) }))
+		assert.Contains(t, tb.fatal, `payload: {"id": 1}`)
+	})
+
+	t.Run("TestSubscriber", func(t *testing.T) {
+		s, topics := setupEmulator(t, "orders", "payments")
+		tb := &fakeTB{name: "TestOrders"}
+		defer tb.finish()
+
+		var ts *snstesting.TestSubscriber
+		assert.True(t, tb.run(func() {
+			ts = snstesting.NewMultiTestSubscriber(tb, s.Config(), []string{"orders", "payments"}, fastPolling)
+		}))
+
+		publishTo(t, s, topics["orders"], "created")
+		publishTo(t, s, topics["payments"], "paid")
+
+		var msg *snstesting.Message
+		assert.True(t, tb.run(func() { msg = ts.ReceiveMatching(snstesting.FromTopic("payments"), 5*time.Second) }))
+		assert.Equal(t, "paid", msg.Message)
+
+		assert.False(t, tb.run(func() { ts.ExpectNoMessage(time.Second) }))
+		assert.Contains(t, tb.fatal, snstesting.ErrUnexpectedMessage.Error())
+
+		var msgs []*snstesting.Message
+		publishTo(t, s, topics["orders"], "shipped")
+		assert.True(t, tb.run(func() { msgs = ts.Drain(time.Second) }))
+		if assert.Len(t, msgs, 1, "unexpected message is consumed") {
+			assert.Equal(t, "shipped", msgs[0].Message)
+		}
+
+		assert.False(t, tb.run(func() { ts.ReceiveMatching(snstesting.FromTopic("orders"), time.Second) }))
+		assert.Contains(t, tb.fatal, snstesting.ErrTimeout.Error())
+		assert.Contains(t, tb.fatal, "snstesting diagnosis: no delivery problems found", "timeout is diagnosed")
+
+		publishTo(t, s, topics["orders"], "not a json")
+		assert.True(t, tb.run(func() { msgs = ts.ReceiveBatch(1) }))
+		if assert.Len(t, msgs, 1) {
+			var v map[string]interface{}
+			assert.False(t, tb.run(func() { ts.Decode(msgs[0], &v) }))
+			assert.Contains(t, tb.fatal, "payload: not a json")
+		}
+	})
+
+	t.Run("Receiver", func(t *testing.T) {
+		s, topics := setupEmulator(t, "orders")
+		tb := &fakeTB{name: "TestOrders"}
+		defer tb.finish()
+
+		var r *snstesting.Receiver[int]
+		assert.True(t, tb.run(func() {
+			r = snstesting.NewReceiver[int](snstesting.NewTestSubscriber(tb, s.Config(), "orders", fastPolling))
+		}))
+
+		publishTo(t, s, topics["orders"], "1")
+		publishTo(t, s, topics["orders"], "2")
+		var got int
+		assert.True(t, tb.run(func() { got = r.ReceiveMatching(func(v int) bool { return v > 1 }, 5*time.Second) }))
+		assert.Equal(t, 2, got)
+
+		var first *int
+		assert.True(t, tb.run(func() { first = r.Receive() }))
+		assert.Equal(t, 1, *first)
+
+		publishTo(t, s, topics["orders"], "one")
+		assert.True(t, tb.run(func() { r.ExpectNoMessage(time.Second, func(v int) bool { return v > 0 }) }), "undecodable message doesn't match")
+		assert.False(t, tb.run(func() { r.Receive() }))
+		assert.Contains(t, tb.fatal, "payload: one")
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		s, _ := setupEmulator(t, "orders")
+		tb := &fakeTB{name: "TestOrders"}
+
+		var st *snstesting.Stream
+		assert.True(t, tb.run(func() { st = snstesting.NewTestSubscriber(tb, s.Config(), "orders", fastPolling).Stream() }))
+
+		tb.finish()
+		select {
+		case <-st.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream not stopped by cleanup")
+		}
+		assert.Empty(t, s.Backend.QueueURLs())
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		s, _ := setupEmulator(t, "orders")
+		ctx, cancel := context.WithCancel(context.Background())
+		tb := contextTB{fakeTB: &fakeTB{name: "TestOrders"}, ctx: ctx}
+
+		var ts *snstesting.TestSubscriber
+		assert.True(t, tb.run(func() { ts = snstesting.NewTestSubscriber(tb, s.Config(), "orders", fastPolling) }))
+
+		cancel()
+		assert.False(t, tb.run(func() { ts.Receive() }))
+		assert.Contains(t, tb.fatal, context.Canceled.Error())
+
+		tb.fatal = ""
+		tb.finish()
+		assert.Empty(t, tb.fatal, "cleanup doesn't use cancelled context of the test")
+		assert.Empty(t, tb.errors)
+		assert.Empty(t, s.Backend.QueueURLs())
+	})
+
+	t.Run("leaks", func(t *testing.T) {
+		s, _ := setupEmulator(t, "orders")
+		tb := &fakeTB{name: "TestOrders"}
+		ledger := filepath.Join(t.TempDir(), "leaks.jsonl")
+
+		var ts *snstesting.TestSubscriber
+		assert.True(t, tb.run(func() {
+			ts = snstesting.NewTestSubscriber(tb, s.Config(), "orders", fastPolling,
+				snstesting.WithCleanupTimeout(time.Second), snstesting.WithLeakLedger(ledger))
+		}))
+
+		s.Close()
+		tb.finish()
+		assert.Empty(t, tb.fatal, "t.Fatal is not called from cleanup")
+		if assert.Len(t, tb.errors, 2) {
+			assert.Contains(t, tb.errors[0], "leaked subscription "+ts.Subscriber.Config.SubscriptionARN)
+			assert.Contains(t, tb.errors[1], "leaked queue "+ts.Subscriber.Config.QueueURL)
+		}
+
+		leaks, err := snstesting.ReadLedger(ledger)
+		assert.NoError(t, err)
+		if assert.Len(t, leaks, 2) {
+			assert.Equal(t, ts.Subscriber.Config.SubscriptionARN, leaks[0].SubscriptionARN)
+			assert.Equal(t, ts.Subscriber.Config.QueueURL, leaks[1].QueueURL)
+			assert.Equal(t, "TestOrders", leaks[1].TestName)
+		}
+	})
+
+	t.Run("emulator", func(t *testing.T) {
+		tb := &fakeTB{name: "TestOrders"}
+
+		var cfg aws.Config
+		assert.True(t, tb.run(func() { cfg = emulator.Start(tb) }))
+		_, err := sns.NewFromConfig(cfg).CreateTopic(context.Background(), &sns.CreateTopicInput{Name: aws.String("orders")})
+		assert.NoError(t, err)
+
+		tb.finish()
+		noRetries := func(o *sns.Options) { o.Retryer = aws.NopRetryer{} }
+		_, err = sns.NewFromConfig(cfg, noRetries).CreateTopic(context.Background(), &sns.CreateTopicInput{Name: aws.String("orders")})
+		assert.Error(t, err, "emulator stopped")
+	})
+}
@@ -0,0 +1,242 @@
+package snstesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// ErrTopicNotFound is returned (wrapped) by TopicResolver that does not know given topic.
+var ErrTopicNotFound = errors.New("topic not found")
+
+// TopicResolver turns topic name into topic ARN.
+// Implementations should wrap ErrTopicNotFound when topic is unknown to them, so they can be chained with Resolvers.
+type TopicResolver interface {
+	ResolveTopic(ctx context.Context, topicName string) (string, error)
+}
+
+// TopicResolverFunc is an adapter to use ordinary functions as TopicResolver.
+type TopicResolverFunc func(ctx context.Context, topicName string) (string, error)
+
+// ResolveTopic calls f(ctx, topicName).
+func (f TopicResolverFunc) ResolveTopic(ctx context.Context, topicName string) (string, error) {
+	return f(ctx, topicName)
+}
+
+// Resolvers chains multiple resolvers, first one that knows the topic wins.
+// Resolution stops on first error that is not ErrTopicNotFound.
+func Resolvers(resolvers ...TopicResolver) TopicResolver {
+	return TopicResolverFunc(func(ctx context.Context, topicName string) (string, error) {
+		var errs []error
+		for _, r := range resolvers {
+			topicArn, err := r.ResolveTopic(ctx, topicName)
+			if err == nil {
+				return topicArn, nil
+			}
+			if !errors.Is(err, ErrTopicNotFound) {
+				return "", err
+			}
+			errs = append(errs, err)
+		}
+		return "", fmt.Errorf("%w: %s, resolvers said: %v", ErrTopicNotFound, topicName, combineErr(errs...))
+	})
+}
+
+// ARNResolver uses topic name as is, provided it's already SNS topic ARN.
+type ARNResolver struct{}
+
+// ResolveTopic implements TopicResolver.
+func (ARNResolver) ResolveTopic(_ context.Context, topicName string) (string, error) {
+	if !isTopicArn(topicName) {
+		return "", fmt.Errorf("%w: %s is not sns topic arn", ErrTopicNotFound, topicName)
+	}
+	return topicName, nil
+}
+
+// ListTopicsResolver iterates over all topics visible to SNS client looking for matching one.
+// Requires sns:ListTopics permission.
+type ListTopicsResolver struct {
+	SNS   SNSAPI
+	Match TopicMatch
+}
+
+// ResolveTopic implements TopicResolver.
+func (r ListTopicsResolver) ResolveTopic(ctx context.Context, topicName string) (string, error) {
+	return findTopicArn(ctx, r.SNS, topicName, r.Match)
+}
+
+// EnvResolver reads topic ARN from environment variable named after the topic.
+// Variable name is Prefix followed by upper cased topic name with all non-alphanumeric characters replaced by '_',
+// e.g. with 'TOPIC_ARN_' prefix 'orders-created' topic is looked up in 'TOPIC_ARN_ORDERS_CREATED'.
+type EnvResolver struct {
+	Prefix string
+	// LookupEnv defaults to os.LookupEnv.
+	LookupEnv func(string) (string, bool)
+}
+
+// ResolveTopic implements TopicResolver.
+func (r EnvResolver) ResolveTopic(_ context.Context, topicName string) (string, error) {
+	lookupEnv := r.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	name := r.Prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, topicName)
+
+	topicArn, ok := lookupEnv(name)
+	if !ok || topicArn == "" {
+		return "", fmt.Errorf("%w: %s environment variable not set", ErrTopicNotFound, name)
+	}
+	return topicArn, nil
+}
+
+// StaticResolver maps topic names to topic ARNs.
+type StaticResolver map[string]string
+
+// ResolveTopic implements TopicResolver.
+func (r StaticResolver) ResolveTopic(_ context.Context, topicName string) (string, error) {
+	topicArn, ok := r[topicName]
+	if !ok {
+		return "", fmt.Errorf("%w: %s not in static mapping", ErrTopicNotFound, topicName)
+	}
+	return topicArn, nil
+}
+
+func isTopicArn(s string) bool {
+	a, err := arn.Parse(s)
+	return err == nil && a.Service == "sns" && a.Resource != ""
+}
+
+// TopicMatch tells how topic name is compared with names of existing topics.
+type TopicMatch int
+
+const (
+	// MatchExact requires topic name to be equal to the name part of topic ARN. This is the default.
+	MatchExact TopicMatch = iota
+	// MatchGlob treats topic name as a shell pattern, see path.Match for the syntax.
+	MatchGlob
+	// MatchRegexp treats topic name as a regular expression, see regexp package for the syntax.
+	MatchRegexp
+)
+
+func (m TopicMatch) String() string {
+	switch m {
+	case MatchExact:
+		return "exact"
+	case MatchGlob:
+		return "glob"
+	case MatchRegexp:
+		return "regexp"
+	default:
+		return fmt.Sprintf("TopicMatch(%d)", int(m))
+	}
+}
+
+// topicNameFromArn extracts topic name from ARN, e.g. 'arn:aws:sns:us-east-1:123456789012:orders' gives 'orders'.
+func topicNameFromArn(arn string) string {
+	return arn[strings.LastIndex(arn, ":")+1:]
+}
+
+func topicMatcher(topicName string, match TopicMatch) (func(string) bool, error) {
+	switch match {
+	case MatchExact:
+		return func(name string) bool {
+			return name == topicName
+		}, nil
+	case MatchGlob:
+		if _, err := path.Match(topicName, ""); err != nil {
+			return nil, fmt.Errorf("invalid topic pattern %s: %v", topicName, err)
+		}
+		return func(name string) bool {
+			ok, _ := path.Match(topicName, name)
+			return ok
+		}, nil
+	case MatchRegexp:
+		re, err := regexp.Compile(topicName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid topic pattern %s: %v", topicName, err)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, fmt.Errorf("unknown topic match %s", match)
+	}
+}
+
+// findTopicArn iterates over all sns topics looking for the one named topicName.
+// In exact mode the first match wins, in pattern modes all topics are examined and more than one match is an error.
+func findTopicArn(ctx context.Context, cli SNSAPI, topicName string, match TopicMatch) (string, error) {
+	matches, err := topicMatcher(topicName, match)
+	if err != nil {
+		return "", err
+	}
+
+	var found, similar []string
+	var nextToken *string
+	for {
+		out, err := cli.ListTopics(ctx, &sns.ListTopicsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		for _, topic := range out.Topics {
+			if topic.TopicArn == nil {
+				continue
+			}
+			name := topicNameFromArn(*topic.TopicArn)
+			switch {
+			case matches(name):
+				if match == MatchExact {
+					return *topic.TopicArn, nil
+				}
+				found = append(found, *topic.TopicArn)
+			case match == MatchExact && strings.Contains(name, topicName):
+				similar = append(similar, *topic.TopicArn)
+			}
+		}
+
+		nextToken = out.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case len(found) > 1:
+		return "", fmt.Errorf("topic pattern %s is ambiguous, matching topics: %s", topicName, strings.Join(found, ", "))
+	case len(similar) > 0:
+		return "", topicNotFoundError(fmt.Sprintf("topic %s not found, similar topics: %s", topicName, strings.Join(similar, ", ")))
+	default:
+		return "", topicNotFoundError(fmt.Sprintf("topic %s not found", topicName))
+	}
+}
+
+// topicNotFoundError keeps error message of topic lookup as is, while still matching ErrTopicNotFound.
+type topicNotFoundError string
+
+func (e topicNotFoundError) Error() string {
+	return string(e)
+}
+
+func (e topicNotFoundError) Is(target error) bool {
+	return target == ErrTopicNotFound
+}
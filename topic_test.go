@@ -0,0 +1,129 @@
+package snstesting_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/golang/mock/gomock"
+	"github.com/prozz/snstesting"
+	"github.com/prozz/snstesting/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestARNResolver(t *testing.T) {
+	ctx := context.Background()
+
+	topicArn, err := snstesting.ARNResolver{}.ResolveTopic(ctx, "arn:aws:sns:eu-west-1:123456789012:orders")
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", topicArn)
+
+	_, err = snstesting.ARNResolver{}.ResolveTopic(ctx, "orders")
+	assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+
+	_, err = snstesting.ARNResolver{}.ResolveTopic(ctx, "arn:aws:sqs:eu-west-1:123456789012:orders")
+	assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+}
+
+func TestListTopicsResolver(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	SNS := mock.NewMockSNSAPI(ctrl)
+
+	SNS.EXPECT().ListTopics(ctx, &sns.ListTopicsInput{NextToken: nil}).
+		Return(&sns.ListTopicsOutput{
+			Topics: []snstypes.Topic{
+				{TopicArn: aws.String("arn:aws:sns:eu-west-1:123456789012:orders")},
+			},
+		}, nil).Times(2)
+
+	r := snstesting.ListTopicsResolver{SNS: SNS}
+
+	topicArn, err := r.ResolveTopic(ctx, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", topicArn)
+
+	_, err = r.ResolveTopic(ctx, "payments")
+	assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+}
+
+func TestEnvResolver(t *testing.T) {
+	ctx := context.Background()
+
+	r := snstesting.EnvResolver{
+		Prefix: "TOPIC_ARN_",
+		LookupEnv: func(name string) (string, bool) {
+			if name == "TOPIC_ARN_ORDERS_CREATED_V2" {
+				return "arn:aws:sns:eu-west-1:123456789012:orders-created.v2", true
+			}
+			return "", false
+		},
+	}
+
+	topicArn, err := r.ResolveTopic(ctx, "orders-created.v2")
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders-created.v2", topicArn)
+
+	_, err = r.ResolveTopic(ctx, "payments")
+	assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+	assert.Contains(t, err.Error(), "TOPIC_ARN_PAYMENTS")
+
+	t.Setenv("SNSTESTING_TOPIC_ARN_ORDERS", "arn:aws:sns:eu-west-1:123456789012:orders")
+	topicArn, err = snstesting.EnvResolver{Prefix: "SNSTESTING_TOPIC_ARN_"}.ResolveTopic(ctx, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", topicArn)
+}
+
+func TestStaticResolver(t *testing.T) {
+	ctx := context.Background()
+
+	r := snstesting.StaticResolver{
+		"orders": "arn:aws:sns:eu-west-1:123456789012:orders",
+	}
+
+	topicArn, err := r.ResolveTopic(ctx, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", topicArn)
+
+	_, err = r.ResolveTopic(ctx, "payments")
+	assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+}
+
+func TestResolvers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("first known wins", func(t *testing.T) {
+		r := snstesting.Resolvers(
+			snstesting.ARNResolver{},
+			snstesting.StaticResolver{"orders": "arn:aws:sns:eu-west-1:123456789012:orders"},
+			snstesting.StaticResolver{"orders": "arn:aws:sns:eu-west-1:123456789012:other"},
+		)
+
+		topicArn, err := r.ResolveTopic(ctx, "orders")
+		assert.NoError(t, err)
+		assert.Equal(t, "arn:aws:sns:eu-west-1:123456789012:orders", topicArn)
+	})
+
+	t.Run("not found anywhere", func(t *testing.T) {
+		r := snstesting.Resolvers(snstesting.ARNResolver{}, snstesting.StaticResolver{})
+
+		_, err := r.ResolveTopic(ctx, "orders")
+		assert.ErrorIs(t, err, snstesting.ErrTopicNotFound)
+	})
+
+	t.Run("other errors stop resolution", func(t *testing.T) {
+		r := snstesting.Resolvers(
+			snstesting.TopicResolverFunc(func(ctx context.Context, topicName string) (string, error) {
+				return "", assert.AnError
+			}),
+			snstesting.StaticResolver{"orders": "arn:aws:sns:eu-west-1:123456789012:orders"},
+		)
+
+		_, err := r.ResolveTopic(ctx, "orders")
+		assert.True(t, errors.Is(err, assert.AnError))
+	})
+}
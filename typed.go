@@ -0,0 +1,105 @@
+package snstesting
+
+import (
+	"time"
+)
+
+// DecodeJSON strictly decodes JSON payload into T, unknown fields and trailing data are errors.
+func DecodeJSON[T any](payload string) (T, error) {
+	var v T
+	err := decode(JSON(), payload, &v)
+	return v, err
+}
+
+// ReceiveJSON calls receive and strictly decodes Message field of received SNS notification into T.
+// Returns nil when nothing arrived. In case of decoding failure t.Fatal is executed, showing offending payload.
+func ReceiveJSON[T any](t TB, receive ReceiveMessageFn) *T {
+	t.Helper()
+
+	msg := receive()
+	if msg == nil {
+		return nil
+	}
+	v := decodeOrFatal[T](t, JSON(), msg.Message)
+	return &v
+}
+
+// Receiver gives messages decoded into T, in case of any error (decoding failure included) t.Fatal is executed.
+// Payloads are decoded with subscriber's Decoder, see WithDecoder.
+// By default Message field of SNS notification is decoded, see FromBody for alternative.
+type Receiver[T any] struct {
+	Subscriber *TestSubscriber
+	// FromBody decodes whole SQS message body, useful with WithRawMessageDelivery.
+	FromBody bool
+}
+
+// NewReceiver creates Receiver decoding payloads of messages received by TestSubscriber into T.
+func NewReceiver[T any](ts *TestSubscriber) *Receiver[T] {
+	return &Receiver[T]{Subscriber: ts}
+}
+
+// Receive receives single message and decodes it, returns nil when nothing arrived.
+func (r *Receiver[T]) Receive() *T {
+	r.Subscriber.t.Helper()
+
+	msg := r.Subscriber.ReceiveMessage()
+	if msg == nil {
+		return nil
+	}
+	v := r.decode(msg)
+	return &v
+}
+
+// ReceiveMatching waits for message which decoded value satisfies the predicate, see Subscriber.ReceiveMatching.
+func (r *Receiver[T]) ReceiveMatching(match func(T) bool, timeout time.Duration) T {
+	r.Subscriber.t.Helper()
+
+	msg := r.Subscriber.ReceiveMatching(r.predicate(match), timeout)
+	return r.decode(msg)
+}
+
+// ExpectNoMessage fails the test if message which decoded value satisfies any of predicates arrives within the time window.
+// Without predicates, any message is unexpected. See Subscriber.ExpectNoMessage.
+func (r *Receiver[T]) ExpectNoMessage(within time.Duration, match ...func(T) bool) {
+	r.Subscriber.t.Helper()
+
+	predicates := make([]Predicate, len(match))
+	for i, fn := range match {
+		predicates[i] = r.predicate(fn)
+	}
+	r.Subscriber.ExpectNoMessage(within, predicates...)
+}
+
+// predicate matches decoded value of the message. Messages which can't be decoded don't match, on shared topics
+// other producers may publish payloads of different shape.
+func (r *Receiver[T]) predicate(match func(T) bool) Predicate {
+	return func(msg *Message) bool {
+		var v T
+		if err := decode(r.Subscriber.Subscriber.decoder(), r.payload(msg), &v); err != nil {
+			return false
+		}
+		return match(v)
+	}
+}
+
+func (r *Receiver[T]) decode(msg *Message) T {
+	r.Subscriber.t.Helper()
+	return decodeOrFatal[T](r.Subscriber.t, r.Subscriber.Subscriber.decoder(), r.payload(msg))
+}
+
+func (r *Receiver[T]) payload(msg *Message) string {
+	if r.FromBody {
+		return msg.Body
+	}
+	return msg.Message
+}
+
+func decodeOrFatal[T any](t TB, d Decoder, payload string) T {
+	t.Helper()
+
+	var v T
+	if err := decode(d, payload, &v); err != nil {
+		t.Fatalf("%v, payload: %s", err, payload)
+	}
+	return v
+}
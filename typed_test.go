@@ -0,0 +1,76 @@
+package snstesting_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prozz/snstesting"
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		v, err := snstesting.DecodeJSON[order](`{"id": "1", "status": "paid"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, order{ID: "1", Status: "paid"}, v)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := snstesting.DecodeJSON[order](`{"id": "1", "state": "paid"}`)
+		assert.EqualError(t, err, `decode snstesting_test.order failure: json: unknown field "state"`)
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		_, err := snstesting.DecodeJSON[order](`{"id": "1"} {"id": "2"}`)
+		assert.EqualError(t, err, "decode snstesting_test.order failure: unexpected data after JSON value")
+	})
+
+	t.Run("not a json", func(t *testing.T) {
+		_, err := snstesting.DecodeJSON[order](`hello`)
+		assert.Error(t, err)
+	})
+}
+
+func TestReceiveJSON(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		v := snstesting.ReceiveJSON[order](t, func() *snstesting.Message {
+			return &snstesting.Message{Message: `{"id": "1", "status": "paid"}`}
+		})
+		assert.Equal(t, &order{ID: "1", Status: "paid"}, v)
+	})
+
+	t.Run("nothing arrived", func(t *testing.T) {
+		v := snstesting.ReceiveJSON[order](t, func() *snstesting.Message {
+			return nil
+		})
+		assert.Nil(t, v)
+	})
+}
+
+func TestReceiver(t *testing.T) {
+	subscriber := &snstesting.Subscriber{}
+	r := snstesting.NewReceiver[order](snstesting.NewTestSubscriberFrom(t, subscriber))
+
+	subscriber.Release(
+		&snstesting.Message{Message: `{"id": "1", "status": "new"}`},
+		&snstesting.Message{Message: `{"invoice": "1"}`},
+		&snstesting.Message{Message: `{"id": "2", "status": "paid"}`},
+	)
+
+	v := r.ReceiveMatching(func(o order) bool {
+		return o.Status == "paid"
+	}, time.Second)
+	assert.Equal(t, order{ID: "2", Status: "paid"}, v)
+
+	assert.Equal(t, &order{ID: "1", Status: "new"}, r.Receive())
+	assert.Equal(t, `{"invoice": "1"}`, r.Subscriber.ReceiveMessage().Message, "undecodable message doesn't match")
+
+	subscriber.Release(&snstesting.Message{Body: `{"id": "3", "status": "new"}`})
+	r.FromBody = true
+	assert.Equal(t, &order{ID: "3", Status: "new"}, r.Receive())
+}